@@ -0,0 +1,224 @@
+package chroma
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// Checkpoint records the last oplog entry whose statements were durably
+// written, so that a restarted run can skip everything up to it.
+type Checkpoint struct {
+	Index     int        `json:"index"`
+	Timestamp *Timestamp `json:"ts,omitempty"`
+	// Offset is the size of the output file after the entry, for sinks that
+	// write to a file; anything past it is discarded on resume.
+	Offset int64 `json:"offset,omitempty"`
+}
+
+// LoadCheckpoint reads a checkpoint file, returning nil if there is none yet.
+func LoadCheckpoint(name string) (*Checkpoint, error) {
+	data, err := os.ReadFile(name)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoint %s: %w", name, err)
+	}
+
+	var result Checkpoint
+	err = json.Unmarshal(data, &result)
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing checkpoint %s: %w", name, err)
+	}
+
+	return &result, nil
+}
+
+// Save replaces the checkpoint file atomically.
+func (c *Checkpoint) Save(name string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	tmp := name + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("error writing checkpoint %s: %w", name, err)
+	}
+
+	return os.Rename(tmp, name)
+}
+
+// Done reports whether the entry was already converted before the checkpoint.
+// Entries are compared by oplog timestamp when both carry one, and by their
+// position in the input otherwise.
+func (c *Checkpoint) Done(entry Entry) bool {
+	if c == nil {
+		return false
+	}
+
+	if c.Timestamp != nil {
+		if ts, ok := entryTimestamp(entry.Data); ok {
+			return ts.Compare(*c.Timestamp) <= 0
+		}
+	}
+
+	return entry.Index <= c.Index
+}
+
+// CheckpointSink wraps a sink and saves a checkpoint every so many entries,
+// once the statements of every earlier entry have been flushed. When State is
+// set the schema registry is saved alongside, so the two always agree.
+type CheckpointSink struct {
+	Sink
+	State string
+	// Converter owns the registry saved to State, the default one if nil.
+	Converter *Converter
+	name      string
+	every     int
+	current   *Checkpoint
+	completed *Checkpoint
+	saved     *Checkpoint
+	written   int
+	done      bool
+}
+
+// NewCheckpointSink wraps a sink. Over a database sink a checkpoint is also
+// saved whenever a batch commits, so that it always matches what the
+// database holds.
+func NewCheckpointSink(sink Sink, name string, every int) *CheckpointSink {
+	if every <= 0 {
+		every = 1
+	}
+
+	c := &CheckpointSink{Sink: sink, name: name, every: every}
+
+	if db, ok := sink.(*dbSink); ok {
+		db.committed = c.record
+	}
+
+	return c
+}
+
+// Begin is called before an entry is rendered, which is when every earlier
+// entry is known to be complete and the registry does not yet hold the
+// changes of the new one.
+func (c *CheckpointSink) Begin(entry Entry) error {
+	if c.current != nil {
+		c.complete()
+		c.written++
+	}
+
+	if begin, ok := c.Sink.(interface{ Begin(Entry) error }); ok {
+		if err := begin.Begin(entry); err != nil {
+			return err
+		}
+	}
+
+	if c.written >= c.every {
+		if err := c.save(); err != nil {
+			return err
+		}
+	}
+
+	c.current = &Checkpoint{Index: entry.Index}
+
+	if ts, ok := entryTimestamp(entry.Data); ok {
+		c.current.Timestamp = &ts
+	}
+
+	return nil
+}
+
+// Flush is called between entries, as when the input goes quiet, so the
+// entry being written is complete and the checkpoint saved covers it.
+func (c *CheckpointSink) Flush() error {
+	if c.current != nil {
+		c.complete()
+	}
+
+	return c.save()
+}
+
+// complete marks the entry being written as the last completed one.
+func (c *CheckpointSink) complete() {
+	c.completed = c.current
+	c.completed.Offset = c.offset()
+	c.current = nil
+}
+
+func (c *CheckpointSink) save() error {
+	if err := c.Sink.Flush(); err != nil {
+		return err
+	}
+
+	return c.record()
+}
+
+// record saves the checkpoint of the last completed entry, and the registry
+// alongside, unless they are saved already.
+func (c *CheckpointSink) record() error {
+	c.written = 0
+
+	if c.completed == nil || c.completed == c.saved {
+		return nil
+	}
+
+	if c.State != "" {
+		if err := converterOf(c.Converter).SaveState(c.State); err != nil {
+			return err
+		}
+	}
+
+	if err := c.completed.Save(c.name); err != nil {
+		return err
+	}
+
+	c.saved = c.completed
+
+	return nil
+}
+
+// Complete marks the entry being written as finished, once the conversion
+// has ended without error.
+func (c *CheckpointSink) Complete() {
+	if c.current != nil {
+		c.complete()
+	}
+
+	c.done = true
+}
+
+func (c *CheckpointSink) offset() int64 {
+	if file, ok := c.Sink.(*fileSink); ok {
+		return file.offset
+	}
+
+	return 0
+}
+
+// Close saves a final checkpoint only for a completed conversion; after a
+// failure the last saved checkpoint and state still agree with each other,
+// and with the database, which is not sent what came after them.
+func (c *CheckpointSink) Close() error {
+	var err error
+
+	if c.done {
+		err = c.save()
+	} else if db, ok := c.Sink.(*dbSink); ok {
+		db.discard()
+	}
+
+	if closeErr := c.Sink.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
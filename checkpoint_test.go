@@ -0,0 +1,259 @@
+package chroma_test
+
+import (
+	"database/sql"
+	chroma "github.com/Adedunmol/chroma"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckpointDone(t *testing.T) {
+	withTs := map[string]interface{}{"ts": map[string]interface{}{"$timestamp": map[string]interface{}{"t": float64(100), "i": float64(2)}}}
+	withoutTs := map[string]interface{}{}
+
+	cases := []struct {
+		name       string
+		checkpoint *chroma.Checkpoint
+		entry      chroma.Entry
+		want       bool
+	}{
+		{"no checkpoint", nil, chroma.Entry{Index: 0, Data: withoutTs}, false},
+		{"index before", &chroma.Checkpoint{Index: 3}, chroma.Entry{Index: 3, Data: withoutTs}, true},
+		{"index after", &chroma.Checkpoint{Index: 3}, chroma.Entry{Index: 4, Data: withoutTs}, false},
+		{"timestamp before", &chroma.Checkpoint{Index: 0, Timestamp: &chroma.Timestamp{T: 100, I: 2}}, chroma.Entry{Index: 9, Data: withTs}, true},
+		{"timestamp after", &chroma.Checkpoint{Index: 9, Timestamp: &chroma.Timestamp{T: 100, I: 1}}, chroma.Entry{Index: 0, Data: withTs}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.checkpoint.Done(c.entry); got != c.want {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCheckpointSink(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "checkpoint.json")
+	output := filepath.Join(dir, "output.sql")
+
+	oplogs := []byte(`[
+		{"op": "d", "ns": "resume.student", "ts": {"t": 1, "i": 1}, "o": {"_id": "1"}},
+		{"op": "d", "ns": "resume.student", "ts": {"t": 1, "i": 2}, "o": {"_id": "2"}},
+		{"op": "d", "ns": "resume.student", "ts": {"t": 2, "i": 1}, "o": {"_id": "3"}}
+	]`)
+
+	data, err := chroma.ParseJSONArray(oplogs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink := chroma.NewCheckpointSink(chroma.NewFileSink(file), name, 1)
+
+	if err := chroma.Convert(chroma.EntriesFrom(data[:2]), sink); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second entry is never marked complete, as if the run crashed
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := chroma.LoadCheckpoint(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &chroma.Checkpoint{Index: 0, Timestamp: &chroma.Timestamp{T: 1, I: 1}, Offset: int64(len("DELETE FROM student WHERE _id = '1';\n"))}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+
+	resumed, err := chroma.ResumeFileSink(output, got.Offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink = chroma.NewCheckpointSink(resumed, name, 1)
+
+	var remaining []map[string]interface{}
+	for idx, oplog := range data {
+		if !got.Done(chroma.Entry{Index: idx, Data: oplog}) {
+			remaining = append(remaining, oplog)
+		}
+	}
+
+	if err := chroma.Convert(chroma.EntriesFrom(remaining), sink); err != nil {
+		t.Fatal(err)
+	}
+
+	sink.Complete()
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+
+	if len(lines) != 3 {
+		t.Errorf("expected every entry exactly once, got %q", lines)
+	}
+}
+
+func TestCheckpointDBSink(t *testing.T) {
+	chroma.ResetRegistry()
+
+	name := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	data, err := chroma.ParseJSONArray([]byte(`[
+		{"op": "i", "ns": "commit.student", "ts": {"t": 1, "i": 1}, "o": {"_id": "1"}},
+		{"op": "i", "ns": "commit.student", "ts": {"t": 1, "i": 2}, "o": {"_id": "2"}},
+		{"op": "d", "ns": "commit.student", "ts": {"t": 1, "i": 3}, "o": {"_id": "1"}},
+		{"op": "d", "ns": "commit.student", "ts": {"t": 1, "i": 4}, "o": {"_id": "2"}}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDriver.reset("checkpoint")
+
+	db, err := sql.Open("chroma-test", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// batches of two statements, checkpoints far apart
+	sink := chroma.NewCheckpointSink(chroma.NewDBSink(db, 2, 0), name, 1000)
+
+	if err := chroma.Convert(chroma.EntriesFrom(data), sink); err != nil {
+		t.Fatal(err)
+	}
+
+	// the last entry is never marked complete, as if the run crashed
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := chroma.LoadCheckpoint(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first entry commits alone, with its DDL, the next two together
+	if want := (&chroma.Checkpoint{Index: 2, Timestamp: &chroma.Timestamp{T: 1, I: 3}}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+
+	if committed := testDriver.statements("checkpoint"); len(committed) != 5 {
+		t.Fatalf("expected the statements of the checkpointed entries alone, got %q", committed)
+	}
+
+	var remaining []map[string]interface{}
+	for idx, oplog := range data {
+		if !got.Done(chroma.Entry{Index: idx, Data: oplog}) {
+			remaining = append(remaining, oplog)
+		}
+	}
+
+	db, err = sql.Open("chroma-test", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink = chroma.NewCheckpointSink(chroma.NewDBSink(db, 2, 0), name, 1000)
+
+	if err := chroma.Convert(chroma.EntriesFrom(remaining), sink); err != nil {
+		t.Fatal(err)
+	}
+
+	sink.Complete()
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	committed := testDriver.statements("checkpoint")
+	if len(committed) != 6 || committed[5] != "DELETE FROM student WHERE _id = '2'" {
+		t.Errorf("expected every entry exactly once, got %q", committed)
+	}
+}
+
+func TestCheckpointIdleFlush(t *testing.T) {
+	chroma.ResetRegistry()
+
+	name := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	data, err := chroma.ParseJSONArray([]byte(`[
+		{"op": "i", "ns": "idle.student", "o": {"_id": "1"}},
+		{"op": "i", "ns": "idle.student", "o": {"_id": "2"}}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDriver.reset("idle")
+
+	db, err := sql.Open("chroma-test", "idle")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink := chroma.NewCheckpointSink(chroma.NewDBSink(db, 100, 0), name, 1000)
+
+	// the input stays open, as when following a file, until the idle
+	// flush has committed both entries
+	entries := make(chan chroma.Entry)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- chroma.Convert(entries, sink)
+	}()
+
+	for idx, oplog := range data {
+		entries <- chroma.Entry{Index: idx, Data: oplog}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(testDriver.statements("idle")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(entries)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if committed := testDriver.statements("idle"); len(committed) != 4 {
+		t.Fatalf("expected both entries committed by the idle flush, got %q", committed)
+	}
+
+	got, err := chroma.LoadCheckpoint(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (&chroma.Checkpoint{Index: 1}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
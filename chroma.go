@@ -0,0 +1,426 @@
+package chroma
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type Handler interface {
+	Parse(map[string]interface{}) error
+	String() string
+}
+
+const WORKERS = 5
+
+var NoFileFound = errors.New("no file found")
+
+type Options struct {
+	Input   string
+	Output  string
+	Dialect string
+	Config  string
+	Include []string
+	Exclude []string
+	// Since and Until leave out the entries before and after a point in
+	// time, an oplog ts as t,i or a wall clock time.
+	Since   string
+	Until   string
+	Apply   bool
+	Driver  string
+	DSN     string
+	Batch   int
+	Retries int
+
+	Checkpoint      string
+	CheckpointEvery int
+
+	Follow bool
+	Poll   time.Duration
+
+	State   string
+	SeedDDL string
+	SeedDSN string
+
+	SchemaLock string
+	Strict     bool
+	DeadLetter string
+
+	// Format selects the output of the validate, stats and schema commands.
+	Format string
+	// Stats names a file the statistics of a conversion are written to as JSON.
+	Stats string
+	// Undo names a file the statements reversing a conversion are written to.
+	Undo string
+	// Compact folds the changes to each document into their net effect.
+	Compact bool
+	// SoftDelete marks deleted rows of every namespace instead of deleting
+	// them, where the config does not set up soft deletes itself.
+	SoftDelete bool
+	// History keeps the versions of the rows of every namespace, where the
+	// config does not set up history tables itself.
+	History bool
+	// Metadata stamps the rows of every namespace with the entry they came
+	// from, where the config does not set up metadata columns itself.
+	Metadata bool
+}
+
+// run converts the input, reporting the schema lock and the statistics of
+// the run to stderr.
+func run(options Options, stderr io.Writer) error {
+	if options.Input == "" {
+		return NoFileFound
+	}
+
+	if options.Output == "" {
+		options.Output = "output.sql"
+	}
+
+	// compaction holds entries back, which neither a followed input nor a
+	// checkpoint can wait for
+	if options.Compact && (options.Follow || options.Checkpoint != "") {
+		return fmt.Errorf("-compact cannot be used with -f or -checkpoint")
+	}
+
+	// a resumed run needs the registry of the checkpoint to know which DDL
+	// was written already
+	if options.Checkpoint != "" && options.State == "" {
+		return fmt.Errorf("-checkpoint needs -state")
+	}
+
+	converter, err := setup(options)
+	if err != nil {
+		return err
+	}
+
+	converter.Resume, err = loadCheckpoint(options)
+	if err != nil {
+		return err
+	}
+
+	converter.Lock, err = openSchemaLock(options)
+	if err != nil {
+		return err
+	}
+
+	if options.Undo != "" {
+		converter.Undo = NewUndo()
+	}
+
+	if converter.Lock != nil {
+		defer converter.Lock.Report(stderr)
+
+		if closer, ok := converter.Lock.DeadLetter.(io.Closer); ok {
+			defer closer.Close()
+		}
+	}
+
+	sink, err := openSink(options, converter.Resume)
+	if err != nil {
+		return err
+	}
+
+	var checkpoints *CheckpointSink
+
+	if options.Checkpoint != "" {
+		checkpoints = NewCheckpointSink(sink, options.Checkpoint, options.CheckpointEvery)
+		checkpoints.State = options.State
+		checkpoints.Converter = converter
+		sink = checkpoints
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	err = converter.convertEntries(ctx, func(fn func(Entry) error) error {
+		return converter.readInput(ctx, options, fn)
+	}, sink)
+
+	if err == nil && checkpoints != nil {
+		checkpoints.Complete()
+	}
+
+	if closeErr := sink.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil && checkpoints == nil && options.State != "" {
+		err = converter.SaveState(options.State)
+	}
+
+	// a failed run is undone as far as it got
+	if converter.Undo != nil {
+		if undoErr := writeUndo(options.Undo, converter.Undo); err == nil {
+			err = undoErr
+		}
+	}
+
+	stats := converter.Stats
+
+	if err != nil {
+		stats.Errors++
+	}
+
+	stats.Finish()
+	stats.Print(stderr)
+
+	if options.Stats != "" {
+		if statsErr := writeStats(options.Stats, stats); err == nil {
+			err = statsErr
+		}
+	}
+
+	return err
+}
+
+func writeStats(name string, stats *Stats) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if err := stats.WriteJSON(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func writeUndo(name string, undo *Undo) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if err := undo.WriteScript(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// setup builds the converter of a command from the options shared by every
+// command: dialect, config, saved and seeded registry, and namespace filter.
+func setup(options Options) (*Converter, error) {
+	converter := NewConverter()
+
+	if err := converter.SetDialect(options.Dialect); err != nil {
+		return nil, err
+	}
+
+	if options.Config != "" {
+		c, err := LoadConfig(os.DirFS(filepath.Dir(options.Config)), filepath.Base(options.Config))
+		if err != nil {
+			return nil, err
+		}
+
+		converter.Config = c
+	}
+
+	if options.SoftDelete && converter.Config.SoftDelete == nil {
+		converter.Config.SoftDelete = &SoftDelete{}
+	}
+
+	if options.History && converter.Config.History == nil {
+		converter.Config.History = &History{}
+	}
+
+	if options.Metadata && converter.Config.Metadata == nil {
+		converter.Config.Metadata = &Metadata{}
+	}
+
+	if options.State != "" {
+		state, err := LoadState(options.State)
+		if err != nil {
+			return nil, err
+		}
+
+		converter.Restore(state)
+	}
+
+	if err := seed(converter, options); err != nil {
+		return nil, err
+	}
+
+	filter, err := NewFilter(options.Include, options.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	converter.Filter = filter
+	converter.Compact = options.Compact
+
+	converter.Window, err = NewWindow(options.Since, options.Until)
+	if err != nil {
+		return nil, err
+	}
+
+	return converter, nil
+}
+
+// seed tells the registry about tables that already exist in the target.
+func seed(converter *Converter, options Options) error {
+	if options.SeedDDL != "" {
+		ddl, err := readFile(options.SeedDDL)
+		if err != nil {
+			return err
+		}
+
+		if err := converter.SeedFromDDL(ddl); err != nil {
+			return err
+		}
+	}
+
+	if options.SeedDSN != "" {
+		if options.Driver == "" {
+			return fmt.Errorf("-seed-dsn needs -driver")
+		}
+
+		db, err := sql.Open(options.Driver, options.SeedDSN)
+		if err != nil {
+			return fmt.Errorf("error opening database: %w", err)
+		}
+		defer db.Close()
+
+		if err := converter.SeedFromDB(db); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func openSchemaLock(options Options) (*SchemaLock, error) {
+	if options.SchemaLock == "" {
+		return nil, nil
+	}
+
+	result, err := LoadSchemaLock(options.SchemaLock)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Strict = options.Strict
+
+	if options.DeadLetter != "" {
+		file, err := os.OpenFile(options.DeadLetter, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		result.DeadLetter = file
+	}
+
+	return result, nil
+}
+
+func loadCheckpoint(options Options) (*Checkpoint, error) {
+	if options.Checkpoint == "" {
+		return nil, nil
+	}
+
+	return LoadCheckpoint(options.Checkpoint)
+}
+
+func openSink(options Options, resume *Checkpoint) (Sink, error) {
+	if !options.Apply && resume == nil {
+		fileHandle, err := os.Create(options.Output)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewFileSink(fileHandle), nil
+	}
+
+	if !options.Apply {
+		return ResumeFileSink(options.Output, resume.Offset)
+	}
+
+	if options.Driver == "" || options.DSN == "" {
+		return nil, fmt.Errorf("-apply needs both -driver and -dsn")
+	}
+
+	db, err := sql.Open(options.Driver, options.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error connecting to database: %w", err)
+	}
+
+	return NewDBSink(db, options.Batch, options.Retries), nil
+}
+
+func splitList(value string) []string {
+	var result []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// readFile reads a file named on the command line, relative or absolute.
+func readFile(name string) ([]byte, error) {
+	return openFile(os.DirFS(filepath.Dir(name)), filepath.Base(name))
+}
+
+func openFile(fileSystem fs.FS, name string) ([]byte, error) {
+	file, err := fileSystem.Open(name)
+
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %w", name, err)
+	}
+
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", name, err)
+	}
+
+	return data, nil
+}
+
+// SeparateOperations parses oplog entries into their handlers, leaving out
+// those without one and those hooks skip.
+func SeparateOperations(oplogs []map[string]interface{}) ([]Handler, error) {
+	var handlers []Handler
+
+	for _, oplog := range oplogs {
+		if !defaultConverter.handles(oplog) {
+			continue
+		}
+
+		handler, err := defaultConverter.Parse(oplog)
+
+		if errors.Is(err, SkipEntry) {
+			continue
+		}
+
+		if err != nil {
+			return handlers, err
+		}
+
+		handlers = append(handlers, handler)
+	}
+
+	return handlers, nil
+}
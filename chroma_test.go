@@ -1,4 +1,4 @@
-package main_test
+package chroma_test
 
 import (
 	chroma "github.com/Adedunmol/chroma"
@@ -39,36 +39,63 @@ func TestSeparateOperations(t *testing.T) {
 		t.Errorf("got unexpected error: %v", err)
 	}
 
-	got := chroma.SeparateOperations(oplogsMap)
+	got, err := chroma.SeparateOperations(oplogsMap)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	want := []chroma.Handler{
-		&chroma.Insert{
+	want := []chroma.Insert{
+		{
 			Database: "test",
 			Table:    "student",
 			Columns: []chroma.KeyValue{
 				{"_id", "635b79e231d82a8ab1de863b"},
+				{"date_of_birth", "2000-01-30"},
+				{"is_graduated", false},
 				{"name", "Selena Miller"},
 				{"roll_no", float64(51)},
-				{"is_graduated", false},
-				{"date_of_birth", "2000-01-30"},
 			}},
-		&chroma.Insert{
+		{
 			Database: "test",
 			Table:    "student",
 			Columns: []chroma.KeyValue{
 				{"_id", "14798c213f273a7ca2cf5174"},
+				{"date_of_birth", "2001-03-23"},
+				{"is_graduated", true},
 				{"name", "George Smith"},
 				{"roll_no", float64(21)},
-				{"is_graduated", true},
-				{"date_of_birth", "2001-0-23"},
 			}},
 	}
 
 	if len(got) != len(want) {
-		t.Errorf("got %v, want %v", len(got), len(want))
+		t.Fatalf("got %v, want %v", len(got), len(want))
+	}
+
+	for idx := range want {
+		if idx >= len(got) {
+			break
+		}
+
+		insert, ok := got[idx].(*chroma.Insert)
+		if !ok {
+			t.Errorf("got %T, want *chroma.Insert", got[idx])
+			continue
+		}
+
+		if insert.Database != want[idx].Database || insert.Table != want[idx].Table || !reflect.DeepEqual(insert.Columns, want[idx].Columns) {
+			t.Errorf("got %s.%s %v, want %s.%s %v", insert.Database, insert.Table, insert.Columns,
+				want[idx].Database, want[idx].Table, want[idx].Columns)
+		}
+	}
+}
+
+func TestSeparateOperationsError(t *testing.T) {
+	oplogs, err := chroma.ParseJSONArray([]byte(`[{"op": "i", "ns": "student", "o": {"_id": "1"}}]`))
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	if !reflect.DeepEqual(&got[0], &want[0]) {
-		t.Errorf("got %#v, want %#v", &got[0], &want[0])
+	if _, err := chroma.SeparateOperations(oplogs); err == nil {
+		t.Errorf("expected an error for an entry without a valid namespace")
 	}
 }
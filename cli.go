@@ -0,0 +1,234 @@
+package chroma
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Exit codes of the chroma command.
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+	ExitUsage   = 2
+	// ExitInvalid means the input was read but did not pass validation or
+	// the schema lock.
+	ExitInvalid = 3
+)
+
+var InvalidInput = errors.New("invalid input")
+
+type command struct {
+	name    string
+	summary string
+	flags   func(*flag.FlagSet, *Options)
+	run     func(options Options, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{
+		name:    "convert",
+		summary: "convert an oplog to SQL statements (the default)",
+		flags: func(fs *flag.FlagSet, o *Options) {
+			inputFlags(fs, o)
+			registryFlags(fs, o)
+			fs.StringVar(&o.Output, "o", "output.sql", "output file")
+			fs.BoolVar(&o.Apply, "apply", false, "execute statements against -dsn instead of writing -o")
+			fs.StringVar(&o.DSN, "dsn", "", "data source name used by -apply")
+			fs.IntVar(&o.Batch, "batch", 100, "statements per transaction with -apply")
+			fs.IntVar(&o.Retries, "retries", 3, "retries of a batch on transient errors with -apply")
+			fs.StringVar(&o.Checkpoint, "checkpoint", "", "file tracking the last written entry, to resume from after a restart; needs -state")
+			fs.IntVar(&o.CheckpointEvery, "checkpoint-every", 1000, "entries between checkpoints")
+			fs.BoolVar(&o.Follow, "f", false, "follow the input as it grows, one JSON entry per line")
+			fs.DurationVar(&o.Poll, "poll", 500*time.Millisecond, "how often -f checks the input for new lines")
+			fs.StringVar(&o.SchemaLock, "schema-lock", "", "approved schema; changes outside it are reported")
+			fs.BoolVar(&o.Strict, "strict", false, "fail on entries that change the schema outside -schema-lock")
+			fs.StringVar(&o.DeadLetter, "dead-letter", "", "with -strict, write offending entries here instead of failing")
+			fs.StringVar(&o.Stats, "stats", "", "also write the statistics of the run to this file as JSON")
+			fs.StringVar(&o.Undo, "undo", "", "also write a script reversing the run to this file, newest change first")
+			fs.BoolVar(&o.Compact, "compact", false, "fold the changes to each document into their net effect; not with -f or -checkpoint")
+		},
+		run: func(options Options, _, stderr io.Writer) error {
+			return run(options, stderr)
+		},
+	},
+	{
+		name:    "plan",
+		summary: "print the DDL a conversion would emit, without converting",
+		flags: func(fs *flag.FlagSet, o *Options) {
+			inputFlags(fs, o)
+			registryFlags(fs, o)
+		},
+		run: toStdout(runPlan),
+	},
+	{
+		name:    "validate",
+		summary: "check every entry of an oplog without converting it",
+		flags: func(fs *flag.FlagSet, o *Options) {
+			fs.StringVar(&o.Input, "i", "", "input file")
+			fs.StringVar(&o.Config, "config", "", "type mapping config file")
+			fs.StringVar(&o.Format, "format", "text", "output format (text, json)")
+		},
+		run: toStdout(runValidate),
+	},
+	{
+		name:    "stats",
+		summary: "report per namespace what converting an oplog would do, without writing it",
+		flags: func(fs *flag.FlagSet, o *Options) {
+			inputFlags(fs, o)
+			registryFlags(fs, o)
+			fs.StringVar(&o.Format, "format", "text", "output format (text, json)")
+			fs.BoolVar(&o.Compact, "compact", false, "fold the changes to each document into their net effect")
+		},
+		run: toStdout(runStats),
+	},
+	{
+		name:    "schema",
+		summary: "print the schema registry, after scanning -i if given",
+		flags: func(fs *flag.FlagSet, o *Options) {
+			inputFlags(fs, o)
+			registryFlags(fs, o)
+			fs.StringVar(&o.Format, "format", "ddl", "output format (ddl, json)")
+		},
+		run: toStdout(runSchema),
+	},
+}
+
+// toStdout adapts a command that writes to stdout alone.
+func toStdout(run func(Options, io.Writer) error) func(Options, io.Writer, io.Writer) error {
+	return func(options Options, stdout, _ io.Writer) error {
+		return run(options, stdout)
+	}
+}
+
+func inputFlags(fs *flag.FlagSet, o *Options) {
+	fs.StringVar(&o.Input, "i", "", "input file, a JSON array or one JSON entry per line")
+	fs.StringVar(&o.Dialect, "dialect", "mysql", "target SQL dialect (mysql, postgres)")
+	fs.StringVar(&o.Config, "config", "", "type mapping config file")
+	listFlag(fs, &o.Include, "include", "comma-separated namespaces to convert (glob, or /regex/)")
+	listFlag(fs, &o.Exclude, "exclude", "comma-separated namespaces to skip (glob, or /regex/)")
+	fs.StringVar(&o.Since, "since", "", "skip entries before this oplog ts (t,i) or wall clock time (RFC 3339 or YYYY-MM-DD HH:MM[:SS], UTC)")
+	fs.StringVar(&o.Until, "until", "", "skip entries after this oplog ts (t,i) or wall clock time, both ends included")
+	fs.BoolVar(&o.SoftDelete, "soft-delete", false, "mark deleted rows with deleted_at and is_deleted instead of deleting them, unless -config says otherwise")
+	fs.BoolVar(&o.History, "history", false, "also keep every version of each row in a <table>_history table, unless -config says otherwise")
+	fs.BoolVar(&o.Metadata, "metadata", false, "stamp written rows with the _oplog_ts, _oplog_wall and _oplog_op of their entry, unless -config says otherwise")
+}
+
+func registryFlags(fs *flag.FlagSet, o *Options) {
+	fs.StringVar(&o.State, "state", "", "file keeping the schema registry between runs")
+	fs.StringVar(&o.SeedDDL, "seed-ddl", "", "DDL file describing tables that already exist in the target")
+	fs.StringVar(&o.SeedDSN, "seed-dsn", "", "data source name of a target database to read existing tables from")
+	fs.StringVar(&o.Driver, "driver", "", "database/sql driver for -dsn and -seed-dsn, mysql or postgres")
+}
+
+func listFlag(fs *flag.FlagSet, list *[]string, name, usage string) {
+	fs.Func(name, usage, func(value string) error {
+		*list = append(*list, splitList(value)...)
+		return nil
+	})
+}
+
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+
+	return command{}, false
+}
+
+// Execute runs the chroma command line and returns its exit code. Without
+// arguments it prints the commands; without a command name the arguments are
+// taken as flags of convert.
+func Execute(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return ExitUsage
+	}
+
+	name := "convert"
+
+	if !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		if len(args) > 0 {
+			if c, ok := findCommand(args[0]); ok {
+				c.flagSet(&Options{}, stderr).Usage()
+				return ExitOK
+			}
+		}
+
+		usage(stderr)
+		return ExitOK
+	}
+
+	c, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(stderr, "chroma: unknown command %q\n", name)
+		usage(stderr)
+		return ExitUsage
+	}
+
+	var options Options
+
+	fs := c.flagSet(&options, stderr)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "chroma %s: unexpected arguments: %s\n", c.name, strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return ExitUsage
+	}
+
+	err := c.run(options, stdout, stderr)
+
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, NoFileFound):
+		fmt.Fprintf(stderr, "chroma %s: %v, use -i\n", c.name, err)
+		return ExitUsage
+	case errors.Is(err, InvalidInput), errors.Is(err, SchemaViolation):
+		fmt.Fprintf(stderr, "chroma %s: %v\n", c.name, err)
+		return ExitInvalid
+	default:
+		fmt.Fprintf(stderr, "chroma %s: %v\n", c.name, err)
+		return ExitFailure
+	}
+}
+
+func (c command) flagSet(options *Options, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("chroma "+c.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	c.flags(fs, options)
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: chroma %s [flags]\n\n%s\n\nflags:\n", c.name, c.summary)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: chroma <command> [flags]\n\ncommands:\n")
+
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+
+	fmt.Fprintf(w, "\nrun \"chroma help <command>\" for the flags of a command\n")
+	fmt.Fprintf(w, "\nexit codes: %d ok, %d failure, %d usage, %d invalid input\n", ExitOK, ExitFailure, ExitUsage, ExitInvalid)
+}
@@ -0,0 +1,119 @@
+package chroma_test
+
+import (
+	"bytes"
+	chroma "github.com/Adedunmol/chroma"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecute(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "oplog.json")
+	output := filepath.Join(dir, "output.sql")
+	undo := filepath.Join(dir, "undo.sql")
+
+	err := os.WriteFile(input, []byte(`[
+		{"op": "i", "ns": "cli.student", "o": {"_id": "1", "name": "John Doe"}},
+		{"op": "d", "ns": "cli.student", "o": {"_id": "1"}}
+	]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"op": "i", "ns": "cli", "o": {"_id": "1"}}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	unsupported := filepath.Join(dir, "unsupported.json")
+	if err := os.WriteFile(unsupported, []byte(`{"op": "i", "ns": "cli.student", "o": {"_id": "1", "tags": ["a"]}}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+	}{
+		{"convert", []string{"convert", "-i", input, "-o", output}, chroma.ExitOK, ""},
+		{"convert by default", []string{"-i", input, "-o", output}, chroma.ExitOK, ""},
+		{"convert with undo", []string{"-i", input, "-o", output, "-undo", undo}, chroma.ExitOK, ""},
+		{"convert unsupported value", []string{"-i", unsupported, "-o", filepath.Join(dir, "unsupported.sql")}, chroma.ExitFailure, ""},
+		{"checkpoint without state", []string{"-i", input, "-o", filepath.Join(dir, "resumed.sql"), "-checkpoint", filepath.Join(dir, "checkpoint.json")}, chroma.ExitFailure, ""},
+		{"missing input", []string{"convert"}, chroma.ExitUsage, ""},
+		{"unknown command", []string{"export"}, chroma.ExitUsage, ""},
+		{"unknown flag", []string{"plan", "-x"}, chroma.ExitUsage, ""},
+		{"help", []string{"help", "plan"}, chroma.ExitOK, ""},
+		{"plan", []string{"plan", "-i", input}, chroma.ExitOK, "CREATE TABLE IF NOT EXISTS student"},
+		{"validate", []string{"validate", "-i", input}, chroma.ExitOK, "2 entries ok"},
+		{"validate invalid", []string{"validate", "-i", invalid}, chroma.ExitInvalid, "entry 0"},
+		{"stats", []string{"stats", "-i", input}, chroma.ExitOK, "cli.student"},
+		{"schema", []string{"schema", "-i", input, "-format", "json"}, chroma.ExitOK, `"student"`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			chroma.ResetRegistry()
+
+			var stdout, stderr bytes.Buffer
+
+			code := chroma.Execute(c.args, &stdout, &stderr)
+
+			if code != c.code {
+				t.Errorf("got exit code %d, want %d: %s", code, c.code, stderr.String())
+			}
+
+			if !strings.Contains(stdout.String(), c.stdout) {
+				t.Errorf("expected output to contain: %s, got %s", c.stdout, stdout.String())
+			}
+		})
+	}
+
+	t.Run("usage without arguments", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+
+		if code := chroma.Execute(nil, &stdout, &stderr); code != chroma.ExitUsage {
+			t.Errorf("got exit code %d, want %d", code, chroma.ExitUsage)
+		}
+
+		if !strings.Contains(stderr.String(), "commands:") {
+			t.Errorf("expected the commands on stderr, got %q", stderr.String())
+		}
+	})
+
+	t.Run("report to stderr", func(t *testing.T) {
+		chroma.ResetRegistry()
+
+		var stdout, stderr bytes.Buffer
+
+		if code := chroma.Execute([]string{"-i", input, "-o", output}, &stdout, &stderr); code != chroma.ExitOK {
+			t.Fatalf("got exit code %d: %s", code, stderr.String())
+		}
+
+		if !strings.Contains(stderr.String(), "entries: 2") {
+			t.Errorf("expected the statistics on stderr, got %q", stderr.String())
+		}
+	})
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(got), "DELETE FROM student") {
+		t.Errorf("expected output file to contain: %s, got %s", "DELETE FROM student", got)
+	}
+
+	got, err = os.ReadFile(undo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "INSERT INTO student (_id, name) VALUES ('1', 'John Doe');\nDELETE FROM student WHERE _id = '1';\n"; string(got) != want {
+		t.Errorf("got undo script %q, want %q", got, want)
+	}
+}
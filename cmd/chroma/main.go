@@ -0,0 +1,14 @@
+// Command chroma converts a MongoDB oplog into SQL statements.
+package main
+
+import (
+	"os"
+
+	"github.com/Adedunmol/chroma"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	os.Exit(chroma.Execute(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,216 @@
+package chroma
+
+// compactor folds the entries of each row into their net effect: an insert
+// followed by updates becomes one insert of the final values, updates merge
+// into one, and an insert that is deleted again leaves nothing, unless
+// deletes of its namespace are soft. Rows come
+// out in the order they were first seen. Entries it cannot fold, such as
+// commands, hand over everything held so far before them, so that the
+// order around them is kept.
+type compactor struct {
+	config Config
+	stats  *Stats
+	send   func(Entry) error
+	rows   map[string]*netChange
+	order  []*netChange
+}
+
+// netChange is what the entries of a row folded into so far.
+type netChange struct {
+	entries []Entry
+	// folded counts the input entries behind entries.
+	folded int
+	last   Entry
+}
+
+func newCompactor(config Config, stats *Stats, send func(Entry) error) *compactor {
+	return &compactor{config: config, stats: stats, send: send, rows: make(map[string]*netChange)}
+}
+
+// add folds an entry into the change of its row.
+func (c *compactor) add(entry Entry) error {
+	op, _ := entry.Data["op"].(string)
+
+	if op != "insert" && op != "update" && op != "delete" {
+		return c.barrier(entry)
+	}
+
+	id, ok := rowID(c.config, getNamespace(entry.Data), identity(entry.Data))
+	if !ok {
+		return c.barrier(entry)
+	}
+
+	change, ok := c.rows[id]
+	if !ok {
+		change = &netChange{}
+		c.rows[id] = change
+		c.order = append(c.order, change)
+	}
+
+	_, soft := c.config.softDelete(getNamespace(entry.Data))
+
+	change.fold(entry, soft)
+
+	return nil
+}
+
+func (c *compactor) barrier(entry Entry) error {
+	if err := c.flush(); err != nil {
+		return err
+	}
+
+	return c.send(entry)
+}
+
+// flush sends the net changes held so far.
+func (c *compactor) flush() error {
+	for _, change := range c.order {
+		entries := change.net()
+
+		for idx := len(entries); idx < change.folded; idx++ {
+			c.stats.Compact(change.last)
+		}
+
+		for _, entry := range entries {
+			if err := c.send(entry); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.rows = make(map[string]*netChange)
+	c.order = nil
+
+	return nil
+}
+
+// fold adds an entry to the change. Soft deletes keep the row, so what came
+// before them stays.
+func (n *netChange) fold(entry Entry, soft bool) {
+	n.folded++
+	n.last = entry
+
+	var last *Entry
+	if len(n.entries) > 0 {
+		last = &n.entries[len(n.entries)-1]
+	}
+
+	switch entry.Data["op"] {
+	case "update":
+		set, unset, ok := diffOf(entry.Data)
+
+		if !ok || last == nil {
+			break
+		}
+
+		switch last.Data["op"] {
+		case "insert":
+			object, _ := last.Data["o"].(map[string]interface{})
+			if object == nil {
+				break
+			}
+
+			for field, value := range set {
+				object[field] = value
+			}
+			for field := range unset {
+				delete(object, field)
+			}
+
+			last.Size += entry.Size
+			stampOf(last.Data, entry.Data)
+			return
+		case "update":
+			lastSet, lastUnset, ok := diffOf(last.Data)
+			if !ok {
+				break
+			}
+
+			for field, value := range set {
+				lastSet[field] = value
+				delete(lastUnset, field)
+			}
+			for field, value := range unset {
+				lastUnset[field] = value
+				delete(lastSet, field)
+			}
+
+			diff := make(map[string]interface{})
+			if len(lastSet) > 0 {
+				diff["u"] = lastSet
+			}
+			if len(lastUnset) > 0 {
+				diff["d"] = lastUnset
+			}
+
+			last.Data["o"] = map[string]interface{}{"$v": 2, "diff": diff}
+			last.Size += entry.Size
+			stampOf(last.Data, entry.Data)
+			return
+		}
+	case "delete":
+		if soft {
+			break
+		}
+
+		// a delete makes the updates before it moot, and takes back an
+		// insert made in the same window
+		for len(n.entries) > 0 && n.entries[len(n.entries)-1].Data["op"] == "update" {
+			n.entries = n.entries[:len(n.entries)-1]
+		}
+
+		if len(n.entries) > 0 && n.entries[len(n.entries)-1].Data["op"] == "insert" {
+			n.entries = n.entries[:len(n.entries)-1]
+			return
+		}
+	}
+
+	n.entries = append(n.entries, entry)
+}
+
+// stampOf carries the ts and wall clock time of a newer entry over to the
+// entry it was folded into, so that rows stamped with their oplog entry
+// show the last change made to them.
+func stampOf(oplog, newer map[string]interface{}) {
+	for _, field := range []string{"ts", "wall"} {
+		if value, ok := newer[field]; ok {
+			oplog[field] = value
+		}
+	}
+}
+
+// net returns the entries a row folded into.
+func (n *netChange) net() []Entry {
+	return n.entries
+}
+
+// diffOf returns the fields an update sets and unsets, if its diff does
+// nothing else.
+func diffOf(oplog map[string]interface{}) (map[string]interface{}, map[string]interface{}, bool) {
+	object, _ := oplog["o"].(map[string]interface{})
+	diff, ok := object["diff"].(map[string]interface{})
+	if !ok {
+		return nil, nil, false
+	}
+
+	set := make(map[string]interface{})
+	unset := make(map[string]interface{})
+
+	for operation, fields := range diff {
+		document, ok := fields.(map[string]interface{})
+		if !ok {
+			return nil, nil, false
+		}
+
+		switch operation {
+		case "u":
+			set = document
+		case "d":
+			unset = document
+		default:
+			return nil, nil, false
+		}
+	}
+
+	return set, unset, true
+}
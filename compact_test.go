@@ -0,0 +1,70 @@
+package chroma_test
+
+import (
+	chroma "github.com/Adedunmol/chroma"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	oplogs := `{"op": "i", "ns": "compact.student", "o": {"_id": "1", "name": "John", "age": 20}}
+{"op": "u", "ns": "compact.student", "o": {"$v": 2, "diff": {"u": {"name": "Jane"}}}, "o2": {"_id": "1"}}
+{"op": "u", "ns": "compact.student", "o": {"$v": 2, "diff": {"d": {"age": false}}}, "o2": {"_id": "1"}}
+{"op": "i", "ns": "compact.student", "o": {"_id": "2", "name": "Joe"}}
+{"op": "u", "ns": "compact.student", "o": {"$v": 2, "diff": {"u": {"name": "Ann", "age": 30}}}, "o2": {"_id": "3"}}
+{"op": "u", "ns": "compact.student", "o": {"$v": 2, "diff": {"d": {"age": false}}}, "o2": {"_id": "3"}}
+{"op": "u", "ns": "compact.student", "o": {"$v": 2, "diff": {"u": {"name": "Amy"}}}, "o2": {"_id": "3"}}
+{"op": "d", "ns": "compact.student", "o": {"_id": "2"}}
+{"op": "u", "ns": "compact.student", "o": {"$v": 2, "diff": {"u": {"name": "Al"}}}, "o2": {"_id": "4"}}
+{"op": "d", "ns": "compact.student", "o": {"_id": "4"}}`
+
+	t.Run("net effect per document", func(t *testing.T) {
+		converter := chroma.NewConverter()
+		converter.Compact = true
+
+		got := collect(t, converter, oplogs)
+
+		want := []string{
+			"INSERT INTO student (_id, name) VALUES ('1', 'Jane');",
+			"UPDATE student SET name = 'Amy', age = NULL WHERE _id = '3'",
+			"DELETE FROM student WHERE _id = '4'",
+		}
+
+		if !reflect.DeepEqual(got[len(got)-len(want):], want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+
+		if converter.Stats.Compacted != 7 {
+			t.Errorf("got %d compacted, want %d", converter.Stats.Compacted, 7)
+		}
+	})
+
+	t.Run("commands keep their place", func(t *testing.T) {
+		converter := chroma.NewConverter()
+		converter.Compact = true
+		converter.Handle("c", func(*chroma.Converter) chroma.Handler { return &noteHandler{} })
+
+		got := collect(t, converter, `{"op": "i", "ns": "compact.teacher", "o": {"_id": "1", "name": "John"}}
+{"op": "c", "ns": "compact.$cmd", "o": {"msg": "command"}}
+{"op": "u", "ns": "compact.teacher", "o": {"$v": 2, "diff": {"u": {"name": "Jane"}}}, "o2": {"_id": "1"}}`)
+
+		want := []string{
+			"INSERT INTO teacher (_id, name) VALUES ('1', 'John');",
+			"-- command",
+			"UPDATE teacher SET name = 'Jane' WHERE _id = '1'",
+		}
+
+		if !reflect.DeepEqual(got[len(got)-len(want):], want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("off by default", func(t *testing.T) {
+		got := collect(t, chroma.NewConverter(), oplogs)
+
+		if n := len(got) - strings.Count(strings.Join(got, "\n"), "CREATE "); n != 10 {
+			t.Errorf("got %d statements, want %d", n, 10)
+		}
+	})
+}
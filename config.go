@@ -0,0 +1,285 @@
+package chroma
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Config describes how Mongo values are mapped onto SQL columns.
+type Config struct {
+	// Types maps a Mongo type (string, double, int, bool, object, array) to a SQL type.
+	Types map[string]string `json:"types"`
+	// Namespaces holds per db.collection overrides.
+	Namespaces map[string]NamespaceConfig `json:"namespaces"`
+	Renames    Renames                    `json:"renames"`
+	// Transforms masks fields per db.collection, keyed by source field name.
+	Transforms map[string]map[string]Transform `json:"transforms"`
+	// Hooks rewrite the entries of every namespace, before those of the
+	// namespace itself.
+	Hooks []HookConfig `json:"hooks"`
+	// SoftDelete marks deleted rows of every namespace instead of deleting
+	// them.
+	SoftDelete *SoftDelete `json:"soft_delete"`
+	// History keeps the versions of the rows of every namespace.
+	History *History `json:"history"`
+	// Metadata stamps the rows of every namespace with the entry they came
+	// from.
+	Metadata *Metadata `json:"metadata"`
+}
+
+// Renames maps Mongo names onto SQL names. Collection renames take a full
+// db.collection on both sides and win over database renames.
+type Renames struct {
+	Databases   map[string]string `json:"databases"`
+	Collections map[string]string `json:"collections"`
+	Fields      map[string]string `json:"fields"`
+}
+
+// NamespaceConfig holds the overrides of one db.collection. SoftDelete,
+// History and Metadata replace the setting for every namespace as a whole
+// when set, and the names left empty in either take their defaults.
+type NamespaceConfig struct {
+	Columns map[string]ColumnConfig `json:"columns"`
+	Hooks   []HookConfig            `json:"hooks"`
+	// Key lists the source fields that identify a row, such as a shard key
+	// and _id. Updates and deletes match on these alone, in this order;
+	// without it they match on every field of o2 or o.
+	Key []string `json:"key"`
+	// SoftDelete marks deleted rows of the namespace instead of deleting
+	// them.
+	SoftDelete *SoftDelete `json:"soft_delete"`
+	// History keeps the versions of the rows of the namespace.
+	History *History `json:"history"`
+	// Metadata stamps the rows of the namespace with the entry they came
+	// from.
+	Metadata *Metadata `json:"metadata"`
+}
+
+// override returns a copy of the setting of a namespace, or of the one for
+// every namespace when the namespace has none, ready for its defaults.
+func override[T any](all, namespace *T) (*T, bool) {
+	setting := all
+
+	if namespace != nil {
+		setting = namespace
+	}
+
+	if setting == nil {
+		return nil, false
+	}
+
+	result := *setting
+
+	return &result, true
+}
+
+// defaults fills in the names a setting leaves empty.
+func defaults(names map[*string]string) {
+	for name, value := range names {
+		if *name == "" {
+			*name = value
+		}
+	}
+}
+
+type ColumnConfig struct {
+	Type    string      `json:"type"`
+	Rename  string      `json:"rename"`
+	Exclude bool        `json:"exclude"`
+	Default interface{} `json:"default"`
+	NotNull bool        `json:"not_null"`
+}
+
+var (
+	ConfigError = errors.New("invalid config")
+	KeyError    = errors.New("incomplete key")
+	mongoTypes  = map[string]bool{"string": true, "double": true, "int": true, "bool": true, "object": true, "array": true}
+)
+
+func LoadConfig(fileSystem fs.FS, name string) (Config, error) {
+	var result Config
+
+	data, err := openFile(fileSystem, name)
+	if err != nil {
+		return result, err
+	}
+
+	err = json.Unmarshal(data, &result)
+
+	if err != nil {
+		return result, fmt.Errorf("error parsing config %s: %w", name, err)
+	}
+
+	for mongoType := range result.Types {
+		if !mongoTypes[mongoType] {
+			return result, fmt.Errorf("%w: unknown type %s in %s", ConfigError, mongoType, name)
+		}
+	}
+
+	for ns, namespace := range result.Namespaces {
+		if _, err := extractNamespace(ns); err != nil {
+			return result, fmt.Errorf("%w: %s in %s", err, ns, name)
+		}
+
+		for _, hook := range namespace.Hooks {
+			if err := hook.validate(); err != nil {
+				return result, fmt.Errorf("%w for %s in %s", err, ns, name)
+			}
+		}
+	}
+
+	for _, hook := range result.Hooks {
+		if err := hook.validate(); err != nil {
+			return result, fmt.Errorf("%w in %s", err, name)
+		}
+	}
+
+	for ns, transforms := range result.Transforms {
+		for field, transform := range transforms {
+			if err := transform.validate(); err != nil {
+				return result, fmt.Errorf("%w for %s.%s in %s", err, ns, field, name)
+			}
+		}
+	}
+
+	for source, target := range result.Renames.Collections {
+		if _, err := extractNamespace(source); err != nil {
+			return result, fmt.Errorf("%w: %s in %s", err, source, name)
+		}
+		if _, err := extractNamespace(target); err != nil {
+			return result, fmt.Errorf("%w: %s in %s", err, target, name)
+		}
+	}
+
+	return result, nil
+}
+
+// SetConfig sets the config of the default converter.
+func SetConfig(c Config) {
+	defaultConverter.Config = c
+}
+
+// columns drops excluded fields and applies renames for the namespace.
+func (c Config) columns(ns string, columns []KeyValue) []KeyValue {
+	var result []KeyValue
+
+	for _, entry := range columns {
+		column, ok := c.Namespaces[ns].Columns[entry.Key]
+
+		if ok && column.Exclude {
+			continue
+		}
+
+		result = append(result, KeyValue{Key: c.rename(ns, entry.Key), Value: entry.Value})
+	}
+
+	return result
+}
+
+func (c Config) rename(ns, key string) string {
+	column, ok := c.Namespaces[ns].Columns[key]
+
+	if ok && column.Rename != "" {
+		return column.Rename
+	}
+
+	if field, ok := c.Renames.Fields[key]; ok {
+		return field
+	}
+
+	return key
+}
+
+// key picks the fields of a condition that identify a row in the
+// namespace, renamed for its table.
+func (c Config) key(ns string, condition []KeyValue) ([]KeyValue, error) {
+	fields := c.Namespaces[ns].Key
+
+	if len(fields) == 0 {
+		result := c.columns(ns, condition)
+		if len(result) == 0 {
+			return nil, fmt.Errorf("%w: every key field of %s is excluded", KeyError, ns)
+		}
+
+		return result, nil
+	}
+
+	values := make(map[string]interface{})
+	for _, entry := range condition {
+		values[entry.Key] = entry.Value
+	}
+
+	var result []KeyValue
+
+	for _, field := range fields {
+		value, ok := values[field]
+		if !ok {
+			return nil, fmt.Errorf("%w: key field %s missing from %s", KeyError, field, ns)
+		}
+
+		result = append(result, KeyValue{Key: c.rename(ns, field), Value: value})
+	}
+
+	return result, nil
+}
+
+// namespace returns the target database and table for a source namespace.
+func (c Config) namespace(database, collection string) (string, string) {
+	if target, ok := c.Renames.Collections[database+"."+collection]; ok {
+		match, err := extractNamespace(target)
+		if err == nil {
+			return match[1], match[2]
+		}
+	}
+
+	if target, ok := c.Renames.Databases[database]; ok {
+		return target, collection
+	}
+
+	return database, collection
+}
+
+// column finds the override for a column of a target namespace by its
+// source or renamed name.
+func (c Config) column(target, name string) (ColumnConfig, bool) {
+	for ns, namespace := range c.Namespaces {
+		match, err := extractNamespace(ns)
+		if err != nil {
+			continue
+		}
+
+		database, table := c.namespace(match[1], match[2])
+		if database+"."+table != target {
+			continue
+		}
+
+		for key, column := range namespace.Columns {
+			if c.rename(ns, key) == name {
+				return column, true
+			}
+		}
+	}
+
+	return ColumnConfig{}, false
+}
+
+func mongoType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case int, int64:
+		return "int"
+	case float64:
+		return "double"
+	case bool:
+		return "bool"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return ""
+	}
+}
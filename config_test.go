@@ -0,0 +1,227 @@
+package chroma_test
+
+import (
+	"context"
+	"errors"
+	chroma "github.com/Adedunmol/chroma"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const configJSON = `{
+	"types": {
+		"object": "JSON",
+		"array": "JSON"
+	},
+	"namespaces": {
+		"config.student": {
+			"columns": {
+				"dob": {"rename": "date_of_birth", "type": "DATE"},
+				"password": {"exclude": true},
+				"name": {"not_null": true, "default": "unknown"}
+			}
+		}
+	}
+}`
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("load config", func(t *testing.T) {
+		fileSystem := fstest.MapFS{"chroma.json": {Data: []byte(configJSON)}}
+
+		got, err := chroma.LoadConfig(fileSystem, "chroma.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := chroma.ColumnConfig{Rename: "date_of_birth", Type: "DATE"}
+
+		if !reflect.DeepEqual(got.Namespaces["config.student"].Columns["dob"], want) {
+			t.Errorf("got %#v, want %#v", got.Namespaces["config.student"].Columns["dob"], want)
+		}
+	})
+
+	t.Run("reject unknown type", func(t *testing.T) {
+		fileSystem := fstest.MapFS{"chroma.json": {Data: []byte(`{"types": {"decimal128": "DECIMAL"}}`)}}
+
+		_, err := chroma.LoadConfig(fileSystem, "chroma.json")
+
+		if !errors.Is(err, chroma.ConfigError) {
+			t.Errorf("got unexpected error: %v", err)
+		}
+	})
+}
+
+func TestApplyConfig(t *testing.T) {
+	chroma.ResetRegistry()
+
+	fileSystem := fstest.MapFS{"chroma.json": {Data: []byte(configJSON)}}
+
+	c, err := chroma.LoadConfig(fileSystem, "chroma.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chroma.SetConfig(c)
+	defer chroma.SetConfig(chroma.Config{})
+
+	t.Run("insert", func(t *testing.T) {
+		insert := newInsert(t, "config.student", `{"_id": "1", "name": "John Doe", "dob": "2000-01-30", "password": "secret", "address": {"city": "Lagos"}}`)
+		got := insert.String()
+
+		for _, want := range []string{"date_of_birth DATE", "name VARCHAR(255) NOT NULL DEFAULT 'unknown'", "address JSON"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("expected output to contain: %s, got %s", want, got)
+			}
+		}
+
+		if strings.Contains(got, "password") {
+			t.Errorf("expected output to exclude: %s, got %s", "password", got)
+		}
+	})
+
+	t.Run("json values", func(t *testing.T) {
+		insert := newInsert(t, "config.student", `{"_id": "2", "name": "Jane", "address": {"city": "Lagos"}, "tags": ["a", "b"]}`)
+		statements, err := insert.Statements()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := statements[len(statements)-1]
+		want := `INSERT INTO student (_id, address, name, tags) VALUES ('2', '{"city":"Lagos"}', 'Jane', '["a","b"]');`
+
+		if got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+
+		data, err := chroma.ParseJSONMap([]byte(`{
+			"op": "u",
+			"ns": "config.student",
+			"o": {"$v": 2, "diff": {"u": {"address": {"city": "O'Neill"}}}},
+			"o2": {"_id": "2"}
+		}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		update := chroma.NewUpdate()
+		if err := update.Parse(data); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := update.String(), `UPDATE student SET address = '{"city":"O''Neill"}' WHERE _id = '2'`; got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		data, err := chroma.ParseJSONMap([]byte(`{
+			"op": "u",
+			"ns": "config.student",
+			"o": {"$v": 2, "diff": {"u": {"dob": "2000-01-31", "password": "changed"}}},
+			"o2": {"_id": "1"}
+		}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		update := chroma.NewUpdate()
+		err = update.Parse(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []chroma.KeyValue{{Key: "date_of_birth", Value: "2000-01-31"}}
+
+		if !reflect.DeepEqual(update.Columns, want) {
+			t.Errorf("got %#v, want %#v", update.Columns, want)
+		}
+	})
+}
+
+func TestRenames(t *testing.T) {
+	chroma.ResetRegistry()
+
+	chroma.SetConfig(chroma.Config{
+		Renames: chroma.Renames{
+			Databases:   map[string]string{"legacy": "archive"},
+			Collections: map[string]string{"rename.student": "school.students"},
+			Fields:      map[string]string{"dob": "date_of_birth"},
+		},
+		Namespaces: map[string]chroma.NamespaceConfig{
+			"rename.student": {Columns: map[string]chroma.ColumnConfig{"dob": {Type: "DATE"}}},
+		},
+	})
+	defer chroma.SetConfig(chroma.Config{})
+
+	t.Run("insert", func(t *testing.T) {
+		insert := newInsert(t, "rename.student", `{"_id": "1", "dob": "2000-01-30"}`)
+		got := insert.String()
+
+		for _, want := range []string{"CREATE SCHEMA IF NOT EXISTS school;", "CREATE TABLE IF NOT EXISTS students", "date_of_birth DATE", "INSERT INTO students"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("expected output to contain: %s, got %s", want, got)
+			}
+		}
+
+		if !chroma.GetTable("students") || !chroma.GetSchema("school") {
+			t.Errorf("should have registered: %s", "school.students")
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		data, err := chroma.ParseJSONMap([]byte(`{"op": "d", "ns": "legacy.student", "o": {"dob": "2000-01-30"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := chroma.NewDelete()
+		err = got.Parse(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := chroma.Delete{
+			Database:   "archive",
+			Table:      "student",
+			Conditions: []chroma.KeyValue{{Key: "date_of_birth", Value: "2000-01-30"}},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %#v want %#v", got, want)
+		}
+	})
+}
+
+func TestKeyFields(t *testing.T) {
+	converter := chroma.NewConverter()
+	converter.Config = chroma.Config{
+		Renames: chroma.Renames{Fields: map[string]string{"region": "shard_region"}},
+		Namespaces: map[string]chroma.NamespaceConfig{
+			"key.student": {Key: []string{"region", "_id"}},
+		},
+	}
+
+	t.Run("matches on the key fields in order", func(t *testing.T) {
+		got := collect(t, converter, `{"op": "u", "ns": "key.student", "o": {"$v": 2, "diff": {"u": {"name": "Jane"}}}, "o2": {"_id": "1", "region": "eu", "year": 2020}}
+{"op": "d", "ns": "key.student", "o": {"_id": "1", "region": "eu", "year": 2020}}`)
+
+		want := []string{
+			"UPDATE student SET name = 'Jane' WHERE shard_region = 'eu' AND _id = '1'",
+			"DELETE FROM student WHERE shard_region = 'eu' AND _id = '1'",
+		}
+
+		if !reflect.DeepEqual(got[len(got)-2:], want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("missing key field", func(t *testing.T) {
+		err := converter.ConvertFunc(context.Background(), strings.NewReader(`{"op": "d", "ns": "key.student", "o": {"_id": "1"}}`), func(chroma.Statement) error { return nil })
+
+		if !errors.Is(err, chroma.KeyError) {
+			t.Errorf("got %v, want %v", err, chroma.KeyError)
+		}
+	})
+}
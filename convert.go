@@ -0,0 +1,240 @@
+package chroma
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Entry is an oplog entry along with its position in the input.
+type Entry struct {
+	Index int
+	Data  map[string]interface{}
+	// Size is the number of bytes the entry took up in the input.
+	Size int
+}
+
+type parsed struct {
+	seq     int
+	entry   Entry
+	handler Handler
+	err     error
+}
+
+// flushInterval is how long Convert waits for more entries before flushing
+// what it has written so far.
+var flushInterval = time.Second
+
+// Convert converts entries with the default converter.
+func Convert(entries <-chan Entry, sink Sink) error {
+	return defaultConverter.Convert(entries, sink)
+}
+
+// Convert parses entries concurrently and writes their statements to sink in
+// input order. Rendering happens in order as well, since it decides which
+// entry emits the DDL for a table. On error Convert returns straight away;
+// the caller should stop sending and close entries.
+func (c *Converter) Convert(entries <-chan Entry, sink Sink) error {
+	jobs := make(chan parsed, WORKERS*2)
+	results := make(chan parsed, WORKERS*2)
+
+	var workers sync.WaitGroup
+
+	workers.Add(WORKERS)
+	for i := 0; i < WORKERS; i++ {
+		go c.worker(jobs, results, &workers)
+	}
+
+	go func() {
+		seq := 0
+		for entry := range entries {
+			jobs <- parsed{seq: seq, entry: entry}
+			seq++
+		}
+		close(jobs)
+		workers.Wait()
+		close(results)
+	}()
+
+	next := 0
+	pending := make(map[int]parsed)
+
+	idle := time.NewTimer(flushInterval)
+	defer idle.Stop()
+
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				return nil
+			}
+
+			pending[result.seq] = result
+
+			for {
+				ready, ok := pending[next]
+				if !ok {
+					break
+				}
+
+				delete(pending, next)
+				next++
+
+				if err := c.emit(ready, sink); err != nil {
+					go drain(results)
+					return err
+				}
+			}
+
+			idle.Reset(flushInterval)
+		case <-idle.C:
+			// the input has gone quiet, as when following a file
+			if err := sink.Flush(); err != nil {
+				go drain(results)
+				return err
+			}
+		}
+	}
+}
+
+// drain lets the workers of a failed conversion finish once their input closes.
+func drain(results <-chan parsed) {
+	for range results {
+	}
+}
+
+func (c *Converter) worker(jobs <-chan parsed, results chan<- parsed, workers *sync.WaitGroup) {
+	defer workers.Done()
+
+	for job := range jobs {
+		job.handler, job.err = c.Parse(job.entry.Data)
+		results <- job
+	}
+}
+
+func (c *Converter) emit(result parsed, sink Sink) error {
+	if errors.Is(result.err, SkipEntry) {
+		if dropper, ok := sink.(interface{ Drop(Entry) }); ok {
+			dropper.Drop(result.entry)
+		}
+		return nil
+	}
+
+	if result.err != nil {
+		return fmt.Errorf("entry %d: %w", result.entry.Index, result.err)
+	}
+
+	if c.Lock != nil {
+		ok, err := c.Lock.admit(result.entry, result.handler)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			if rejecter, ok := sink.(interface{ Reject(Entry) }); ok {
+				rejecter.Reject(result.entry)
+			}
+			return nil
+		}
+	}
+
+	if begin, ok := sink.(interface{ Begin(Entry) error }); ok {
+		if err := begin.Begin(result.entry); err != nil {
+			return err
+		}
+	}
+
+	queries, err := statements(result.handler)
+	if err != nil {
+		return fmt.Errorf("entry %d: %w", result.entry.Index, err)
+	}
+
+	before, after := c.history(result.entry, result.handler)
+	queries = append(append(before, queries...), after...)
+
+	for _, query := range queries {
+		query, err := c.sqlHooks(result.entry.Data, query)
+		if err != nil {
+			return fmt.Errorf("entry %d: %w", result.entry.Index, err)
+		}
+
+		if query == "" {
+			continue
+		}
+
+		err = sink.Write(Statement{Index: result.entry.Index, Entry: result.entry.Data, SQL: query})
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Undo != nil {
+		c.Undo.record(c, result.entry, result.handler)
+	}
+
+	// the rows are tracked once undo needs them or the caller sets them up
+	if c.Undo != nil || c.Rows != nil {
+		c.rows().apply(c.Config, result.entry.Data)
+	}
+
+	return nil
+}
+
+// Parse turns an oplog entry into the handler for its operation.
+// Hooks may rewrite the entry and the handler, or return SkipEntry.
+func (c *Converter) Parse(oplog map[string]interface{}) (Handler, error) {
+	if err := c.entryHooks(oplog); err != nil {
+		return nil, err
+	}
+
+	factory, ok := c.factory(oplog["op"])
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", UnknownOp, oplog["op"])
+	}
+
+	handler := factory(c)
+
+	if err := handler.Parse(oplog); err != nil {
+		return nil, err
+	}
+
+	c.applyTransforms(oplog, handler)
+
+	return c.handlerHooks(oplog, handler)
+}
+
+// statements splits a handler's output into individual statements, with
+// the schema changes it needs first.
+func statements(handler Handler) ([]string, error) {
+	if multi, ok := handler.(interface{ Statements() ([]string, error) }); ok {
+		return multi.Statements()
+	}
+
+	var result []string
+
+	if schema, ok := handler.(interface{ ddl() ([]string, error) }); ok {
+		ddl, err := schema.ddl()
+		if err != nil {
+			return nil, err
+		}
+
+		result = ddl
+	}
+
+	return append(result, handler.String()), nil
+}
+
+// EntriesFrom feeds a slice of oplog entries into a channel for Convert.
+func EntriesFrom(oplogs []map[string]interface{}) <-chan Entry {
+	entries := make(chan Entry)
+
+	go func() {
+		for idx, oplog := range oplogs {
+			entries <- Entry{Index: idx, Data: oplog}
+		}
+		close(entries)
+	}()
+
+	return entries
+}
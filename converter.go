@@ -0,0 +1,263 @@
+package chroma
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Converter turns oplog entries into SQL statements. Each converter keeps its
+// own schema registry, so that several can run side by side in one process.
+// Its fields are read while converting and should be set up front.
+type Converter struct {
+	Dialect Dialect
+	Config  Config
+	Filter  Filter
+	// Window leaves out the entries outside a range of time.
+	Window Window
+	// Lock, when set, holds entries to an approved schema.
+	Lock *SchemaLock
+	// Resume skips the entries converted before a checkpoint.
+	Resume *Checkpoint
+	Stats  *Stats
+	// Undo, when set, collects the statements reversing the conversion.
+	Undo *Undo
+	// Rows tracks the rows the conversion touches, for Undo. It is set up
+	// when first needed.
+	Rows *Rows
+	// Compact folds the entries of each row into their net effect before
+	// converting them. It holds entries back until the input ends or an
+	// entry other than an insert, update or delete comes along.
+	Compact bool
+
+	mutex    sync.Mutex
+	tables   map[string]Table
+	schemas  map[string]bool
+	handlers map[string]HandlerFactory
+	hooks    []Hook
+}
+
+// defaultConverter backs the package-level functions, such as Convert and
+// SetDialect, and the handlers made with NewInsert, NewUpdate and NewDelete.
+var defaultConverter = NewConverter()
+
+// NewConverter returns a converter for MySQL with an empty registry and no
+// config.
+func NewConverter() *Converter {
+	return &Converter{
+		Dialect: dialects["mysql"],
+		Stats:   NewStats(),
+		tables:  make(map[string]Table),
+		schemas: make(map[string]bool),
+	}
+}
+
+// rows returns the tracked rows, setting them up on first use.
+func (c *Converter) rows() *Rows {
+	if c.Rows == nil {
+		c.Rows = NewRows()
+	}
+
+	return c.Rows
+}
+
+// keyConditions returns the key of the row an entry changes as conditions
+// on its table, renamed and masked like the columns.
+func (c *Converter) keyConditions(oplog map[string]interface{}) ([]KeyValue, bool) {
+	ns := getNamespace(oplog)
+
+	key, ok := c.Config.rowKey(ns, identity(oplog))
+	if !ok {
+		return nil, false
+	}
+
+	conditions := &Delete{Conditions: c.Config.columns(ns, key)}
+	if len(conditions.Conditions) == 0 {
+		return nil, false
+	}
+
+	c.applyTransforms(oplog, conditions)
+
+	return conditions.Conditions, true
+}
+
+// target returns the table a handler writes to and the converter it was
+// parsed by, for the handlers of inserts, updates and deletes.
+func target(handler Handler) (string, *Converter, bool) {
+	switch h := handler.(type) {
+	case *Insert:
+		return h.Table, h.conv(), true
+	case *Update:
+		return h.Table, converterOf(h.converter), true
+	case *Delete:
+		return h.Table, converterOf(h.converter), true
+	default:
+		return "", nil, false
+	}
+}
+
+// converterOf returns the converter a handler was parsed by, which is the
+// default one for handlers that were built directly.
+func converterOf(c *Converter) *Converter {
+	if c == nil {
+		return defaultConverter
+	}
+
+	return c
+}
+
+func (c *Converter) SetDialect(name string) error {
+	d, err := GetDialect(name)
+	if err != nil {
+		return err
+	}
+
+	c.Dialect = d
+
+	return nil
+}
+
+// Reset forgets every schema and table seen so far.
+func (c *Converter) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.tables = make(map[string]Table)
+	c.schemas = make(map[string]bool)
+}
+
+func (c *Converter) HasTable(name string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, ok := c.tables[name]
+
+	return ok
+}
+
+func (c *Converter) HasSchema(name string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, ok := c.schemas[name]
+
+	return ok
+}
+
+func (c *Converter) Column(table, name string) (Column, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	t, ok := c.tables[table]
+
+	if !ok {
+		return Column{}, false
+	}
+
+	column, ok := t.Schema[name]
+
+	return column, ok
+}
+
+// ConvertReader converts the entries of r, a JSON array or one JSON entry
+// per line, and writes their statements to sink, which it leaves open.
+func (c *Converter) ConvertReader(ctx context.Context, r io.Reader, sink Sink) error {
+	err := c.convertEntries(ctx, func(fn func(Entry) error) error {
+		return c.readEntries(r, fn)
+	}, sink)
+	if err != nil {
+		return err
+	}
+
+	return sink.Flush()
+}
+
+// ConvertTo converts the entries of r and writes their statements to w.
+func (c *Converter) ConvertTo(ctx context.Context, r io.Reader, w io.Writer) error {
+	return c.ConvertReader(ctx, r, NewFileSink(w))
+}
+
+// ConvertFunc converts the entries of r and calls fn with every statement.
+func (c *Converter) ConvertFunc(ctx context.Context, r io.Reader, fn func(Statement) error) error {
+	return c.ConvertReader(ctx, r, SinkFunc(fn))
+}
+
+// convertEntries feeds the entries read by read to Convert, leaving out
+// those before Resume, those Filter or Window reject and those without a handler,
+// and counts them in Stats. With Compact it folds the rest per row first.
+// It stops on the first error or when ctx is done.
+func (c *Converter) convertEntries(ctx context.Context, read func(func(Entry) error) error, sink Sink) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if c.Stats == nil {
+		c.Stats = NewStats()
+	}
+
+	var readErr error
+
+	entries := make(chan Entry)
+
+	send := func(entry Entry) error {
+		select {
+		case entries <- entry:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var compactor *compactor
+
+	if c.Compact {
+		compactor = newCompactor(c.Config, c.Stats, send)
+		send = compactor.add
+	}
+
+	go func() {
+		defer close(entries)
+
+		readErr = read(func(entry Entry) error {
+			c.Stats.Add(entry)
+
+			if c.Resume.Done(entry) {
+				c.Stats.Resume(entry)
+				return nil
+			}
+
+			if !c.Filter.Allow(entry.Data) {
+				c.Stats.Filter(entry)
+				return nil
+			}
+
+			if !c.Window.Allow(entry.Data) {
+				c.Stats.Window(entry)
+				return nil
+			}
+
+			if !c.handles(entry.Data) {
+				c.Stats.Ignore(entry)
+				return nil
+			}
+
+			return send(entry)
+		})
+
+		if readErr == nil && compactor != nil {
+			readErr = compactor.flush()
+		}
+	}()
+
+	err := c.Convert(entries, NewStatsSink(sink, c.Stats))
+	cancel()
+
+	for range entries {
+	}
+
+	if err == nil && readErr != nil && !errors.Is(readErr, context.Canceled) {
+		err = readErr
+	}
+
+	return err
+}
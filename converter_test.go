@@ -0,0 +1,145 @@
+package chroma_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	chroma "github.com/Adedunmol/chroma"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConverter(t *testing.T) {
+	oplog := `{"op": "i", "ns": "lib.student", "o": {"_id": "1", "name": "John Doe"}}
+{"op": "i", "ns": "lib.student", "o": {"_id": "2", "name": "Jane Doe", "roll_no": 12}}
+{"op": "c", "ns": "lib.$cmd", "o": {"create": "teacher"}}
+`
+
+	t.Run("convert to a writer", func(t *testing.T) {
+		converter := chroma.NewConverter()
+
+		var output bytes.Buffer
+
+		if err := converter.ConvertTo(context.Background(), strings.NewReader(oplog), &output); err != nil {
+			t.Fatal(err)
+		}
+
+		got := output.String()
+
+		for _, want := range []string{"CREATE SCHEMA IF NOT EXISTS lib;", "CREATE TABLE IF NOT EXISTS student", "ALTER TABLE student ADD COLUMN roll_no FLOAT;"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("expected %q in %s", want, got)
+			}
+		}
+
+		if !converter.HasTable("student") || !converter.HasSchema("lib") {
+			t.Errorf("expected the converter to register lib.student")
+		}
+
+		if chroma.GetSchema("lib") {
+			t.Errorf("expected the default registry to be left alone")
+		}
+
+		if converter.Stats.Entries != 3 {
+			t.Errorf("got %d entries, want 3", converter.Stats.Entries)
+		}
+	})
+
+	t.Run("report values without a column type", func(t *testing.T) {
+		converter := chroma.NewConverter()
+
+		err := converter.ConvertFunc(context.Background(), strings.NewReader(oplog+`{"op": "i", "ns": "lib.teacher", "o": {"_id": "1", "nickname": null}}`),
+			func(chroma.Statement) error { return nil })
+
+		if err == nil || !strings.HasPrefix(err.Error(), "entry 3: could not create table(teacher)") {
+			t.Errorf("got unexpected error: %v", err)
+		}
+	})
+
+	t.Run("convert to a callback", func(t *testing.T) {
+		converter := chroma.NewConverter()
+
+		var got []int
+
+		err := converter.ConvertFunc(context.Background(), strings.NewReader(oplog), func(statement chroma.Statement) error {
+			got = append(got, statement.Index)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []int{0, 0, 0, 1, 1}
+
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("got entries %v, want %v", got, want)
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		converter := chroma.NewConverter()
+
+		filter, err := chroma.NewFilter(nil, []string{"lib.*"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		converter.Filter = filter
+
+		var output bytes.Buffer
+
+		if err := converter.ConvertTo(context.Background(), strings.NewReader(oplog), &output); err != nil {
+			t.Fatal(err)
+		}
+
+		if output.Len() != 0 {
+			t.Errorf("got %s, want nothing", output.String())
+		}
+	})
+
+	t.Run("concurrent converters", func(t *testing.T) {
+		var wg sync.WaitGroup
+
+		outputs := make([]bytes.Buffer, 4)
+		errs := make([]error, 4)
+
+		for idx := range outputs {
+			converter := chroma.NewConverter()
+
+			if idx%2 == 1 {
+				if err := converter.SetDialect("postgres"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			wg.Add(1)
+			go func(idx int) {
+				defer wg.Done()
+				errs[idx] = converter.ConvertTo(context.Background(), strings.NewReader(oplog), &outputs[idx])
+			}(idx)
+		}
+
+		wg.Wait()
+
+		for idx := range outputs {
+			if errs[idx] != nil {
+				t.Fatal(errs[idx])
+			}
+
+			got := outputs[idx].String()
+
+			if !strings.Contains(got, "CREATE TABLE IF NOT EXISTS student") {
+				t.Errorf("converter %d: expected its own CREATE TABLE in %s", idx, got)
+			}
+
+			want := "name VARCHAR(255)"
+			if idx%2 == 1 {
+				want = "name TEXT"
+			}
+
+			if !strings.Contains(got, want) {
+				t.Errorf("converter %d: expected %q in %s", idx, want, got)
+			}
+		}
+	})
+}
@@ -1,13 +1,23 @@
-package main
+package chroma
 
 import (
+	"errors"
 	"fmt"
 )
 
 type Delete struct {
-	Database  string
-	Table     string
-	Condition KeyValue
+	Database   string
+	Table      string
+	Conditions []KeyValue
+
+	converter *Converter
+	// softDelete, when set, marks the row instead of deleting it, at the
+	// time in deletedAt, and stamps it with its oplog entry if metadata is
+	// set.
+	softDelete *SoftDelete
+	deletedAt  string
+	metadata   *Metadata
+	stamps     []KeyValue
 }
 
 func NewDelete() Delete {
@@ -25,36 +35,67 @@ func (d *Delete) Parse(data map[string]interface{}) error {
 		return err
 	}
 
-	d.Database = match[1]
-	d.Table = match[2]
-	d.Condition = d.getColumns(data)
+	c := converterOf(d.converter)
+
+	d.Database, d.Table = c.Config.namespace(match[1], match[2])
+
+	conditions := d.getColumns(data)
+	if len(conditions) == 0 {
+		return errors.New("no condition found")
+	}
+
+	d.Conditions, err = c.Config.key(ns, conditions)
+	if err != nil {
+		return err
+	}
+
+	if soft, ok := c.Config.softDelete(ns); ok {
+		d.softDelete, d.deletedAt = soft, entryTime(data)
+	}
+
+	if metadata, ok := c.Config.metadata(ns); ok && d.softDelete != nil {
+		d.metadata, d.stamps = metadata, metadata.stamp(data)
+	}
 
 	return nil
 }
 
-func (d *Delete) getColumns(data map[string]interface{}) KeyValue {
+func (d *Delete) getColumns(data map[string]interface{}) []KeyValue {
 
-	var result []KeyValue
-
-	object, ok := data["o"]
+	object, ok := data["o"].(map[string]interface{})
 
 	if !ok {
-		return KeyValue{}
+		return nil
 	}
 
-	for key, value := range object.(map[string]interface{}) {
-		data := KeyValue{Key: key, Value: value}
-		result = append(result, data)
+	return sortedEntries(object)
+}
+
+// String renders the delete, or the update marking the row for a soft delete.
+func (d *Delete) String() string {
+	if d.softDelete != nil {
+		return fmt.Sprintf("UPDATE %s SET %s = %s, %s = TRUE%s WHERE %s", d.Table,
+			d.softDelete.DeletedAt, d.deletedAt, d.softDelete.IsDeleted, stamps(d.stamps), where(d.Conditions))
 	}
 
-	return result[0]
+	insertStr := fmt.Sprintf("DELETE FROM %s WHERE %s", d.Table, where(d.Conditions))
+
+	return insertStr
 }
 
-func (d *Delete) String() string {
+// ddl adds the columns a soft delete sets to its table, if the table lacks
+// them, and returns the statements doing so.
+func (d *Delete) ddl() ([]string, error) {
+	if d.softDelete == nil {
+		return nil, nil
+	}
 
-	conditionStr := fmt.Sprintf("%s = %v", d.Condition.Key, d.Condition.Value)
+	c := converterOf(d.converter)
 
-	insertStr := fmt.Sprintf("DELETE FROM %s WHERE %s", d.Table, conditionStr)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	return insertStr
+	names, definitions := extraColumns(c.Dialect, nil, d.softDelete, d.metadata)
+
+	return c.addColumns(d.Table, names, definitions), nil
 }
@@ -1,4 +1,4 @@
-package main_test
+package chroma_test
 
 import (
 	chroma "github.com/Adedunmol/chroma"
@@ -27,9 +27,9 @@ func TestParseDelete(t *testing.T) {
 	}
 
 	want := chroma.Delete{
-		Database:  "test",
-		Table:     "student",
-		Condition: chroma.KeyValue{Key: "_id", Value: "635b79e231d82a8ab1de863b"},
+		Database:   "test",
+		Table:      "student",
+		Conditions: []chroma.KeyValue{{Key: "_id", Value: "635b79e231d82a8ab1de863b"}},
 	}
 
 	if !reflect.DeepEqual(got, want) {
@@ -59,9 +59,29 @@ func TestStringDelete(t *testing.T) {
 
 	got := delete.String()
 
-	want := "DELETE FROM student WHERE _id = 635b79e231d82a8ab1de863b"
+	want := "DELETE FROM student WHERE _id = '635b79e231d82a8ab1de863b'"
 
 	if len(got) != len(want) {
 		t.Errorf("got %d, want %d", len(got), len(want))
 	}
 }
+
+func TestDeleteShardKey(t *testing.T) {
+	data, err := chroma.ParseJSONMap([]byte(`{"op": "d", "ns": "test.student", "o": {"region": "eu", "_id": "1", "archived": null}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delete := chroma.NewDelete()
+	err = delete.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := delete.String()
+	want := "DELETE FROM student WHERE _id = '1' AND archived IS NULL AND region = 'eu'"
+
+	if got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
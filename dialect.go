@@ -0,0 +1,103 @@
+package chroma
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Dialect describes how column types are rendered for a target database.
+type Dialect struct {
+	Name string
+	// MaxVarchar is the longest VARCHAR the dialect accepts before strings fall back to TextType.
+	MaxVarchar int
+	TextType   string
+	// PreferText maps every string to TextType instead of sizing a VARCHAR.
+	PreferText bool
+	// ModifyColumn formats a column type change from the table, column and new type.
+	ModifyColumn string
+	// ModifyRedefines is set when ModifyColumn replaces the whole column
+	// definition, so constraints have to be repeated.
+	ModifyRedefines bool
+	// TimestampType holds a date and time, such as the time of a soft delete.
+	TimestampType string
+}
+
+// minVarchar is the smallest VARCHAR length emitted for a string column.
+const minVarchar = 255
+
+var (
+	UnknownDialect = errors.New("unknown dialect")
+	dialects       = map[string]Dialect{
+		"mysql": {
+			Name:            "mysql",
+			MaxVarchar:      16383,
+			TextType:        "TEXT",
+			ModifyColumn:    "ALTER TABLE %s MODIFY COLUMN %s %s;",
+			ModifyRedefines: true,
+			TimestampType:   "DATETIME",
+		},
+		"postgres": {
+			Name:          "postgres",
+			MaxVarchar:    10485760,
+			TextType:      "TEXT",
+			PreferText:    true,
+			ModifyColumn:  "ALTER TABLE %s ALTER COLUMN %s TYPE %s;",
+			TimestampType: "TIMESTAMP",
+		},
+	}
+)
+
+func GetDialect(name string) (Dialect, error) {
+	if name == "" {
+		return dialects["mysql"], nil
+	}
+
+	d, ok := dialects[name]
+
+	if !ok {
+		return Dialect{}, fmt.Errorf("%w: %s", UnknownDialect, name)
+	}
+
+	return d, nil
+}
+
+// SetDialect sets the dialect of the default converter.
+func SetDialect(name string) error {
+	return defaultConverter.SetDialect(name)
+}
+
+// stringColumn returns the column type for a string of the given length,
+// leaving headroom so that slightly longer values do not force a widening.
+func (d Dialect) stringColumn(length int) Column {
+	if d.PreferText || length > d.MaxVarchar {
+		return Column{Type: d.TextType}
+	}
+
+	size := minVarchar
+	for size < length {
+		size = size*2 + 1
+	}
+
+	if size > d.MaxVarchar {
+		size = d.MaxVarchar
+	}
+
+	return Column{Type: "VARCHAR", Length: size}
+}
+
+// timestampColumn is the column for a date and time.
+func (d Dialect) timestampColumn() Column {
+	if d.TimestampType == "" {
+		return Column{Type: "TIMESTAMP"}
+	}
+
+	return Column{Type: d.TimestampType}
+}
+
+func (d Dialect) modifyColumn(table, name string, column Column) string {
+	if d.ModifyRedefines {
+		return fmt.Sprintf(d.ModifyColumn, table, name, column.definition())
+	}
+
+	return fmt.Sprintf(d.ModifyColumn, table, name, column)
+}
@@ -0,0 +1,104 @@
+package chroma_test
+
+import (
+	"errors"
+	chroma "github.com/Adedunmol/chroma"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStringColumns(t *testing.T) {
+	t.Run("size varchar from value length", func(t *testing.T) {
+		insert := newInsert(t, "dialect.short", `{"_id": "1", "bio": "short"}`)
+		got := insert.String()
+
+		if !strings.Contains(got, "bio VARCHAR(255)") {
+			t.Errorf("expected output to contain: %s, got %s", "bio VARCHAR(255)", got)
+		}
+	})
+
+	t.Run("leave headroom for long values", func(t *testing.T) {
+		insert := newInsert(t, "dialect.long", `{"_id": "1", "bio": "`+strings.Repeat("a", 600)+`"}`)
+		_ = insert.String()
+
+		got, ok := chroma.GetColumn("long", "bio")
+		if !ok {
+			t.Fatalf("should have found column: %s", "bio")
+		}
+
+		want := chroma.Column{Type: "VARCHAR", Length: 1023}
+		if got != want {
+			t.Errorf("got %#v, want %#v", got, want)
+		}
+	})
+
+	t.Run("widen column when a longer value arrives", func(t *testing.T) {
+		insert := newInsert(t, "dialect.widen", `{"_id": "1", "bio": "short"}`)
+		_ = insert.String()
+
+		insert = newInsert(t, "dialect.widen", `{"_id": "2", "bio": "`+strings.Repeat("a", 300)+`"}`)
+		got := insert.String()
+
+		want := "ALTER TABLE widen MODIFY COLUMN bio VARCHAR(511);"
+		if !strings.Contains(got, want) {
+			t.Errorf("expected output to contain: %s, got %s", want, got)
+		}
+	})
+
+	t.Run("widen column when an update sets a longer value", func(t *testing.T) {
+		long := strings.Repeat("a", 400)
+
+		got := collect(t, chroma.NewConverter(), `{"op": "i", "ns": "dialect.profile", "o": {"_id": "1", "bio": "short"}}
+{"op": "u", "ns": "dialect.profile", "o": {"$v": 2, "diff": {"u": {"bio": "`+long+`", "note": "new"}}}, "o2": {"_id": "1"}}`)
+
+		want := []string{
+			"ALTER TABLE profile MODIFY COLUMN bio VARCHAR(511);",
+			"ALTER TABLE profile ADD COLUMN note VARCHAR(255);",
+			"UPDATE profile SET bio = '" + long + "', note = 'new' WHERE _id = '1'",
+		}
+
+		if !reflect.DeepEqual(got[len(got)-len(want):], want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("map strings to TEXT for postgres", func(t *testing.T) {
+		if err := chroma.SetDialect("postgres"); err != nil {
+			t.Fatal(err)
+		}
+		defer chroma.SetDialect("mysql")
+
+		insert := newInsert(t, "dialect.text", `{"_id": "1", "bio": "short"}`)
+		got := insert.String()
+
+		if !strings.Contains(got, "bio TEXT") {
+			t.Errorf("expected output to contain: %s, got %s", "bio TEXT", got)
+		}
+	})
+
+	t.Run("unknown dialect", func(t *testing.T) {
+		err := chroma.SetDialect("oracle")
+
+		if !errors.Is(err, chroma.UnknownDialect) {
+			t.Errorf("got unexpected error: %v", err)
+		}
+	})
+}
+
+func newInsert(t *testing.T, ns, object string) chroma.Insert {
+	t.Helper()
+
+	data, err := chroma.ParseJSONMap([]byte(`{"op": "i", "ns": "` + ns + `", "o": ` + object + `}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insert := chroma.NewInsert()
+	err = insert.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return insert
+}
@@ -0,0 +1,104 @@
+package chroma
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Filter decides which namespaces are converted. Patterns are globs matched
+// against db.collection, or regular expressions when wrapped in slashes.
+type Filter struct {
+	include []pattern
+	exclude []pattern
+}
+
+type pattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+var PatternError = errors.New("invalid namespace pattern")
+
+func NewFilter(include, exclude []string) (Filter, error) {
+	var filter Filter
+	var err error
+
+	filter.include, err = compilePatterns(include)
+	if err != nil {
+		return filter, err
+	}
+
+	filter.exclude, err = compilePatterns(exclude)
+	if err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func compilePatterns(patterns []string) ([]pattern, error) {
+	var result []pattern
+
+	for _, p := range patterns {
+		if p == "" {
+			continue
+		}
+
+		if len(p) > 1 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+			re, err := regexp.Compile(p[1 : len(p)-1])
+			if err != nil {
+				return result, fmt.Errorf("%w: %s: %v", PatternError, p, err)
+			}
+			result = append(result, pattern{re: re})
+			continue
+		}
+
+		if _, err := path.Match(p, ""); err != nil {
+			return result, fmt.Errorf("%w: %s: %v", PatternError, p, err)
+		}
+		result = append(result, pattern{glob: p})
+	}
+
+	return result, nil
+}
+
+func (p pattern) match(ns string) bool {
+	if p.re != nil {
+		return p.re.MatchString(ns)
+	}
+
+	ok, _ := path.Match(p.glob, ns)
+
+	return ok
+}
+
+// Allow reports whether the oplog entry's namespace passes the filter.
+// Entries without a valid namespace are let through so parsing can report them.
+func (f Filter) Allow(oplog map[string]interface{}) bool {
+	match, err := extractNamespace(getNamespace(oplog))
+
+	if err != nil {
+		return true
+	}
+
+	ns := match[1] + "." + match[2]
+
+	if len(f.include) > 0 && !matchAny(f.include, ns) {
+		return false
+	}
+
+	return !matchAny(f.exclude, ns)
+}
+
+func matchAny(patterns []pattern, ns string) bool {
+	for _, p := range patterns {
+		if p.match(ns) {
+			return true
+		}
+	}
+
+	return false
+}
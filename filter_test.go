@@ -0,0 +1,48 @@
+package chroma_test
+
+import (
+	"errors"
+	chroma "github.com/Adedunmol/chroma"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	cases := []struct {
+		name    string
+		include []string
+		exclude []string
+		ns      string
+		want    bool
+	}{
+		{"no patterns", nil, nil, "test.student", true},
+		{"include glob", []string{"test.*"}, nil, "test.student", true},
+		{"include glob miss", []string{"school.*"}, nil, "test.student", false},
+		{"include regex", []string{"/^test\\.stu/"}, nil, "test.student", true},
+		{"exclude glob", nil, []string{"*.student"}, "test.student", false},
+		{"exclude wins over include", []string{"test.*"}, []string{"test.student"}, "test.student", false},
+		{"invalid namespace passes", []string{"test.*"}, nil, "student", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			filter, err := chroma.NewFilter(c.include, c.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := filter.Allow(map[string]interface{}{"op": "i", "ns": c.ns, "o": map[string]interface{}{}})
+
+			if got != c.want {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := chroma.NewFilter([]string{"/(/"}, nil)
+
+		if !errors.Is(err, chroma.PatternError) {
+			t.Errorf("got unexpected error: %v", err)
+		}
+	})
+}
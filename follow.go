@@ -0,0 +1,137 @@
+package chroma
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// Follow tails a file like tail -f, calling fn with every complete line as it
+// is appended. Partial lines are held back until their newline arrives. The
+// file is read again from the start when it is truncated, and a new file that
+// replaces it (rotation) is read once the rest of the old one has been. Follow
+// returns when ctx is done or fn fails.
+func Follow(ctx context.Context, name string, poll time.Duration, fn func(line []byte) error) error {
+	file, info, err := openFollowed(ctx, name, poll)
+	if err != nil {
+		return err
+	}
+
+	defer func() { file.Close() }()
+
+	reader := bufio.NewReader(file)
+	var partial []byte
+	var offset int64
+
+	for {
+		chunk, err := reader.ReadBytes('\n')
+		offset += int64(len(chunk))
+
+		if err == nil {
+			line := append(partial, chunk...)
+			partial = nil
+
+			if err := fn(bytes.TrimSpace(line)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		partial = append(partial, chunk...)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(poll):
+		}
+
+		current, statErr := os.Stat(name)
+
+		switch {
+		case statErr != nil || !os.SameFile(info, current):
+			// rotated: drain what was written to the old file before it was
+			// replaced, then carry on with the new one
+			if err := drainFollowed(reader, partial, fn); err != nil {
+				return err
+			}
+
+			file.Close()
+
+			file, info, err = openFollowed(ctx, name, poll)
+			if err != nil {
+				return err
+			}
+
+			reader.Reset(file)
+			partial, offset = nil, 0
+		case current.Size() < offset:
+			// truncated in place
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+
+			reader.Reset(file)
+			partial, offset = nil, 0
+		}
+	}
+}
+
+// drainFollowed reads a followed file that was replaced to its end. It will
+// not grow anymore, so a last line without a newline is complete as well.
+func drainFollowed(reader *bufio.Reader, partial []byte, fn func(line []byte) error) error {
+	for {
+		chunk, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		line := bytes.TrimSpace(append(partial, chunk...))
+		partial = nil
+
+		if err == nil || len(line) > 0 {
+			if err := fn(line); err != nil {
+				return err
+			}
+		}
+
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// openFollowed waits for name to exist, since a rotated file may not have
+// been recreated yet.
+func openFollowed(ctx context.Context, name string, poll time.Duration) (*os.File, os.FileInfo, error) {
+	for {
+		file, err := os.Open(name)
+
+		if err == nil {
+			info, err := file.Stat()
+			if err != nil {
+				file.Close()
+				return nil, nil, err
+			}
+
+			return file, info, nil
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package chroma_test
+
+import (
+	"context"
+	chroma "github.com/Adedunmol/chroma"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFollow(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "oplog.jsonl")
+
+	if err := os.WriteFile(name, []byte("first\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lines := make(chan string, 10)
+	done := make(chan error)
+
+	go func() {
+		done <- chroma.Follow(ctx, name, 5*time.Millisecond, func(line []byte) error {
+			lines <- string(line)
+			return nil
+		})
+	}()
+
+	expect(t, lines, "first")
+
+	appendFile(t, name, "sec")
+	appendFile(t, name, "ond\n")
+	expect(t, lines, "second")
+
+	t.Run("truncation", func(t *testing.T) {
+		if err := os.WriteFile(name, []byte("third\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		expect(t, lines, "third")
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		if err := os.Rename(name, name+".1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte("fourth\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		expect(t, lines, "fourth")
+	})
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("got unexpected error: %v", err)
+	}
+}
+
+func TestFollowRotation(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "oplog.jsonl")
+
+	if err := os.WriteFile(name, []byte("line1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lines := make(chan string, 10)
+	done := make(chan error)
+
+	// polling slowly, so that the file is appended to and rotated between
+	// two reads
+	go func() {
+		done <- chroma.Follow(ctx, name, 200*time.Millisecond, func(line []byte) error {
+			lines <- string(line)
+			return nil
+		})
+	}()
+
+	expect(t, lines, "line1")
+
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := file.WriteString("line2\nline3"); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	if err := os.Rename(name, name+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte("line4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, lines, "line2")
+	expect(t, lines, "line3")
+	expect(t, lines, "line4")
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("got unexpected error: %v", err)
+	}
+}
+
+func appendFile(t *testing.T, name, data string) {
+	t.Helper()
+
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+}
+
+func expect(t *testing.T, lines <-chan string, want string) {
+	t.Helper()
+
+	select {
+	case got := <-lines:
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %q", want)
+	}
+}
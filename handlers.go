@@ -0,0 +1,122 @@
+package chroma
+
+import (
+	"sync"
+)
+
+// HandlerFactory makes an empty handler for an entry of one op code, to be
+// filled in by its Parse. The converter is the one doing the conversion.
+type HandlerFactory func(*Converter) Handler
+
+// opNames are the names ParseJSONMap gives the op codes MongoDB writes.
+// Other op codes keep their code as their name.
+var opNames = map[string]string{
+	"i": "insert",
+	"u": "update",
+	"d": "delete",
+	"c": "command",
+	"n": "noop",
+}
+
+// builtinHandlers handle the op codes no handler is registered for.
+var builtinHandlers = map[string]HandlerFactory{
+	"insert": func(c *Converter) Handler { return &Insert{converter: c} },
+	"update": func(c *Converter) Handler { return &Update{converter: c} },
+	"delete": func(c *Converter) Handler { return &Delete{converter: c} },
+}
+
+var (
+	handlersMutex sync.RWMutex
+	handlers      = make(map[string]HandlerFactory)
+)
+
+// RegisterHandler makes every converter handle an op code with handlers
+// made by factory, replacing the built-in handler of i, u or d. Op codes
+// other than i, u, d, c and n are only read from the input once registered.
+// Commands and no-ops are skipped unless a handler is registered for them.
+func RegisterHandler(op string, factory HandlerFactory) {
+	handlersMutex.Lock()
+	defer handlersMutex.Unlock()
+
+	handlers[opName(op)] = factory
+}
+
+// UnregisterHandler takes back the handler RegisterHandler made for an op
+// code, bringing back the built-in handler of i, u or d.
+func UnregisterHandler(op string) {
+	handlersMutex.Lock()
+	defer handlersMutex.Unlock()
+
+	delete(handlers, opName(op))
+}
+
+// Handle makes this converter alone handle an op code with handlers made by
+// factory, taking precedence over RegisterHandler.
+func (c *Converter) Handle(op string, factory HandlerFactory) {
+	if c.handlers == nil {
+		c.handlers = make(map[string]HandlerFactory)
+	}
+
+	c.handlers[opName(op)] = factory
+}
+
+func opName(op string) string {
+	if name, ok := opNames[op]; ok {
+		return name
+	}
+
+	return op
+}
+
+// registered reports whether RegisterHandler knows an op code.
+func registered(op string) bool {
+	handlersMutex.RLock()
+	defer handlersMutex.RUnlock()
+
+	name := opName(op)
+
+	if _, ok := handlers[name]; ok {
+		return true
+	}
+
+	_, ok := builtinHandlers[name]
+
+	return ok
+}
+
+// knows reports whether the converter or RegisterHandler knows an op code.
+func (c *Converter) knows(op string) bool {
+	if _, ok := c.handlers[opName(op)]; ok {
+		return true
+	}
+
+	return registered(op)
+}
+
+// factory finds the handler factory for the named operation of an entry.
+func (c *Converter) factory(op interface{}) (HandlerFactory, bool) {
+	name, _ := op.(string)
+
+	if factory, ok := c.handlers[name]; ok {
+		return factory, true
+	}
+
+	handlersMutex.RLock()
+	defer handlersMutex.RUnlock()
+
+	if factory, ok := handlers[name]; ok {
+		return factory, true
+	}
+
+	factory, ok := builtinHandlers[name]
+
+	return factory, ok
+}
+
+// handles reports whether the converter has a handler for an entry; those
+// it has none for, such as commands by default, are skipped.
+func (c *Converter) handles(oplog map[string]interface{}) bool {
+	_, ok := c.factory(oplog["op"])
+
+	return ok
+}
@@ -0,0 +1,149 @@
+package chroma_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	chroma "github.com/Adedunmol/chroma"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type noteHandler struct {
+	msg string
+}
+
+func (n *noteHandler) Parse(oplog map[string]interface{}) error {
+	object, _ := oplog["o"].(map[string]interface{})
+	n.msg = fmt.Sprint(object["msg"])
+	return nil
+}
+
+func (n *noteHandler) String() string {
+	return "-- " + n.msg
+}
+
+type softDelete struct {
+	chroma.Delete
+}
+
+func (s *softDelete) String() string {
+	return fmt.Sprintf("UPDATE %s SET deleted = TRUE WHERE %s = %v", s.Table, s.Conditions[0].Key, s.Conditions[0].Value)
+}
+
+func collect(t *testing.T, converter *chroma.Converter, oplog string) []string {
+	t.Helper()
+
+	var result []string
+
+	err := converter.ConvertFunc(context.Background(), strings.NewReader(oplog), func(statement chroma.Statement) error {
+		result = append(result, statement.SQL)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return result
+}
+
+func TestHandlers(t *testing.T) {
+	t.Run("handle noops on one converter", func(t *testing.T) {
+		oplog := `{"op": "n", "ns": "", "o": {"msg": "periodic noop"}}` + "\n"
+
+		converter := chroma.NewConverter()
+		converter.Handle("n", func(*chroma.Converter) chroma.Handler { return &noteHandler{} })
+
+		if got, want := collect(t, converter, oplog), []string{"-- periodic noop"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		other := chroma.NewConverter()
+
+		if got := collect(t, other, oplog); len(got) != 0 {
+			t.Errorf("got %v, want noops skipped by other converters", got)
+		}
+
+		if other.Stats.Unhandled != 1 {
+			t.Errorf("got %d unhandled entries, want 1", other.Stats.Unhandled)
+		}
+	})
+
+	t.Run("handle a new op code on one converter", func(t *testing.T) {
+		converter := chroma.NewConverter()
+		converter.Handle("audit", func(*chroma.Converter) chroma.Handler { return &noteHandler{} })
+
+		for _, oplog := range []string{
+			`{"op": "audit", "ns": "handlers.student", "o": {"msg": "checked"}}` + "\n",
+			`[{"op": "audit", "ns": "handlers.student", "o": {"msg": "checked"}}]`,
+		} {
+			var buffer bytes.Buffer
+
+			if err := converter.ConvertReader(context.Background(), strings.NewReader(oplog), chroma.NewFileSink(&buffer)); err != nil {
+				t.Fatalf("got unexpected error: %v", err)
+			}
+
+			if got, want := buffer.String(), "-- checked;\n"; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		}
+
+		other := chroma.NewConverter()
+		err := other.ConvertReader(context.Background(), strings.NewReader(`{"op": "audit", "ns": "handlers.student", "o": {}}`+"\n"), chroma.NewFileSink(io.Discard))
+
+		if !errors.Is(err, chroma.UnknownOp) {
+			t.Errorf("got %v, want an unknown op for other converters", err)
+		}
+	})
+
+	t.Run("override a built-in handler", func(t *testing.T) {
+		converter := chroma.NewConverter()
+		converter.Handle("d", func(*chroma.Converter) chroma.Handler { return &softDelete{} })
+
+		got := collect(t, converter, `{"op": "d", "ns": "handlers.student", "o": {"_id": "1"}}`+"\n")
+		want := []string{"UPDATE student SET deleted = TRUE WHERE _id = 1"}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("register a new op code", func(t *testing.T) {
+		oplog := []byte(`{"op": "audit", "ns": "handlers.student", "o": {"msg": "checked"}}`)
+
+		if _, err := chroma.ParseJSONMap(oplog); err == nil {
+			t.Fatalf("expected an unregistered op code to be rejected")
+		}
+
+		chroma.RegisterHandler("audit", func(*chroma.Converter) chroma.Handler { return &noteHandler{} })
+		defer chroma.UnregisterHandler("audit")
+
+		if _, err := chroma.ParseJSONMap(oplog); err != nil {
+			t.Fatalf("got unexpected error: %v", err)
+		}
+
+		converter := chroma.NewConverter()
+
+		if got, want := collect(t, converter, string(oplog)+"\n"), []string{"-- checked"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		if got := converter.Stats.Namespaces["handlers.student"].Other; got != 1 {
+			t.Errorf("got %d other operations, want 1", got)
+		}
+	})
+
+	t.Run("unregister an op code", func(t *testing.T) {
+		oplog := []byte(`{"op": "audit", "ns": "handlers.student", "o": {"msg": "checked"}}`)
+
+		chroma.RegisterHandler("audit", func(*chroma.Converter) chroma.Handler { return &noteHandler{} })
+		chroma.UnregisterHandler("audit")
+
+		if _, err := chroma.ParseJSONMap(oplog); err == nil {
+			t.Errorf("expected an unregistered op code to be rejected")
+		}
+	})
+}
@@ -0,0 +1,221 @@
+package chroma
+
+import (
+	"fmt"
+	"strings"
+)
+
+// History keeps every version of the rows of a collection in a table of its
+// own, next to the table holding their current state. Every insert, update
+// and delete adds a version, valid from the time of its entry until the
+// next version of the row closes it. The version a delete adds is closed at
+// once, as the row is gone.
+type History struct {
+	// Suffix names the history table after the table, _history by default.
+	Suffix string `json:"suffix"`
+	// ValidFrom, ValidTo, Op and Ts name the columns holding when a version
+	// was valid, the operation that made it and the ts of its entry. They
+	// default to valid_from, valid_to, op and ts.
+	ValidFrom string `json:"valid_from"`
+	ValidTo   string `json:"valid_to"`
+	Op        string `json:"op"`
+	Ts        string `json:"ts"`
+}
+
+// history returns how a namespace keeps the versions of its rows, if it
+// does.
+func (c Config) history(ns string) (*History, bool) {
+	result, ok := override(c.History, c.Namespaces[ns].History)
+	if !ok {
+		return nil, false
+	}
+
+	defaults(map[*string]string{
+		&result.Suffix:    "_history",
+		&result.ValidFrom: "valid_from",
+		&result.ValidTo:   "valid_to",
+		&result.Op:        "op",
+		&result.Ts:        "ts",
+	})
+
+	return result, true
+}
+
+// columns lists the columns a history table adds to the fields of its rows.
+func (h History) columns() []string {
+	return []string{h.ValidFrom, h.ValidTo, h.Op, h.Ts}
+}
+
+func (h History) column(d Dialect, name string) Column {
+	switch name {
+	case h.ValidFrom:
+		column := d.timestampColumn()
+		column.NotNull = true
+		return column
+	case h.ValidTo:
+		return d.timestampColumn()
+	case h.Op:
+		column := d.stringColumn(len("insert"))
+		column.NotNull = true
+		return column
+	default:
+		return tsColumn()
+	}
+}
+
+// history returns the statements adding the version an entry makes to the
+// history table of its namespace and closing the version before it. The
+// version is read from the table holding the current state of the row, so
+// the statements of an insert or update come after the entry's own, and
+// those of a delete before it, while the row is still there. Tables the
+// registry does not know get no versions.
+func (c *Converter) history(entry Entry, handler Handler) (before, after []string) {
+	oplog := entry.Data
+	ns := getNamespace(oplog)
+
+	history, ok := c.Config.history(ns)
+	if !ok {
+		return nil, nil
+	}
+
+	table, _, ok := target(handler)
+	if !ok {
+		return nil, nil
+	}
+
+	conditions, ok := c.keyConditions(oplog)
+	if !ok {
+		return nil, nil
+	}
+
+	columns, ok := c.versionColumns(ns, table)
+	if !ok {
+		return nil, nil
+	}
+
+	name := table + history.Suffix
+	result := c.mirror(table, history, columns)
+
+	validFrom := entryTime(oplog)
+
+	// inserts start a row afresh
+	if oplog["op"] != "insert" {
+		result = append(result, fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s AND %s IS NULL",
+			name, history.ValidTo, validFrom, where(conditions), history.ValidTo))
+	}
+
+	// the version of a delete is closed at once
+	validTo := "NULL"
+	if oplog["op"] == "delete" {
+		validTo = validFrom
+	}
+
+	names := append(append([]string{}, columns...), history.columns()...)
+	values := append(append([]string{}, columns...), validFrom, validTo, literal(oplog["op"]), timestampLiteral(oplog))
+
+	result = append(result, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s WHERE %s",
+		name, strings.Join(names, ", "), strings.Join(values, ", "), table, where(conditions)))
+
+	if oplog["op"] == "delete" {
+		return result, nil
+	}
+
+	return nil, result
+}
+
+// versionColumns lists the columns of a table its history table takes from
+// it: all but those marking soft deletes and stamping rows, _id first and
+// the rest by name. It reports false while the registry does not know the
+// table.
+func (c *Converter) versionColumns(ns, table string) ([]string, bool) {
+	soft, _ := c.Config.softDelete(ns)
+	metadata, _ := c.Config.metadata(ns)
+	extra, _ := extraColumns(c.Dialect, nil, soft, metadata)
+
+	skip := make(map[string]bool)
+	for _, name := range extra {
+		skip[name] = true
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	source, ok := c.tables[table]
+	if !ok {
+		return nil, false
+	}
+
+	fields := make(map[string]interface{})
+	for name := range source.Schema {
+		if !skip[name] {
+			fields[name] = nil
+		}
+	}
+
+	var result []string
+	for _, field := range sortedEntries(fields) {
+		result = append(result, field.Key)
+	}
+
+	return result, true
+}
+
+// mirror makes the history table of a table hold the given columns of it
+// along with those of the versions, creating the history table or adding
+// and widening its columns, and returns the DDL.
+func (c *Converter) mirror(table string, history *History, columns []string) []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	name := table + history.Suffix
+	source := c.tables[table]
+
+	extra, definitions := extraColumns(c.Dialect, history, nil, nil)
+
+	for _, column := range columns {
+		definition := source.Schema[column]
+		definition.PrimaryKey = false
+		definitions[column] = definition
+	}
+
+	names := append(append([]string{}, columns...), extra...)
+
+	versions, ok := c.tables[name]
+
+	if !ok {
+		schema := make(map[string]Column)
+
+		var lines []string
+		for _, column := range names {
+			if _, ok := schema[column]; ok {
+				continue
+			}
+
+			schema[column] = definitions[column]
+			lines = append(lines, strings.Join([]string{"\t", column, definitions[column].definition()}, " "))
+		}
+
+		c.tables[name] = Table{Name: name, Schema: schema}
+
+		return []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n);", name, strings.Join(lines, ",\n"))}
+	}
+
+	var result []string
+
+	for _, column := range columns {
+		existing, ok := versions.Schema[column]
+		wanted := definitions[column]
+
+		if !ok || existing.Type != "VARCHAR" {
+			continue
+		}
+
+		if wanted.Type == c.Dialect.TextType || wanted.Type == "VARCHAR" && wanted.Length > existing.Length {
+			existing.Type, existing.Length = wanted.Type, wanted.Length
+			versions.Schema[column] = existing
+			result = append(result, c.Dialect.modifyColumn(name, column, existing))
+		}
+	}
+
+	return append(result, c.addColumns(name, names, definitions)...)
+}
@@ -0,0 +1,69 @@
+package chroma_test
+
+import (
+	chroma "github.com/Adedunmol/chroma"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	oplogs := `{"op": "i", "ns": "history.student", "o": {"_id": "1", "name": "John"}, "ts": {"$timestamp": {"t": 1700000000, "i": 1}}}
+{"op": "u", "ns": "history.student", "o": {"$v": 2, "diff": {"u": {"name": "Jane"}}}, "o2": {"_id": "1"}, "ts": {"$timestamp": {"t": 1700000060, "i": 2}}}
+{"op": "d", "ns": "history.student", "o": {"_id": "1"}, "ts": {"$timestamp": {"t": 1700000120, "i": 1}}}
+{"op": "u", "ns": "history.student", "o": {"$v": 2, "diff": {"u": {"name": "Ann"}}}, "o2": {"_id": "2"}}`
+
+	t.Run("versions", func(t *testing.T) {
+		converter := chroma.NewConverter()
+		converter.Config = chroma.Config{History: &chroma.History{}}
+
+		got := collect(t, converter, oplogs)
+
+		want := []string{
+			"CREATE SCHEMA IF NOT EXISTS history;",
+			"CREATE TABLE IF NOT EXISTS student (\n\t _id VARCHAR(255) PRIMARY KEY,\n\t name VARCHAR(255)\n);",
+			"INSERT INTO student (_id, name) VALUES ('1', 'John');",
+			"CREATE TABLE IF NOT EXISTS student_history (\n\t _id VARCHAR(255),\n\t name VARCHAR(255),\n\t valid_from DATETIME NOT NULL,\n\t valid_to DATETIME,\n\t op VARCHAR(255) NOT NULL,\n\t ts NUMERIC(20)\n);",
+			"INSERT INTO student_history (_id, name, valid_from, valid_to, op, ts) SELECT _id, name, '2023-11-14 22:13:20', NULL, 'insert', 7301444403200000001 FROM student WHERE _id = '1'",
+			"UPDATE student SET name = 'Jane' WHERE _id = '1'",
+			"UPDATE student_history SET valid_to = '2023-11-14 22:14:20' WHERE _id = '1' AND valid_to IS NULL",
+			"INSERT INTO student_history (_id, name, valid_from, valid_to, op, ts) SELECT _id, name, '2023-11-14 22:14:20', NULL, 'update', 7301444660898037762 FROM student WHERE _id = '1'",
+			"UPDATE student_history SET valid_to = '2023-11-14 22:15:20' WHERE _id = '1' AND valid_to IS NULL",
+			"INSERT INTO student_history (_id, name, valid_from, valid_to, op, ts) SELECT _id, name, '2023-11-14 22:15:20', '2023-11-14 22:15:20', 'delete', 7301444918596075521 FROM student WHERE _id = '1'",
+			"DELETE FROM student WHERE _id = '1'",
+			"UPDATE student SET name = 'Ann' WHERE _id = '2'",
+			"UPDATE student_history SET valid_to = CURRENT_TIMESTAMP WHERE _id = '2' AND valid_to IS NULL",
+			"INSERT INTO student_history (_id, name, valid_from, valid_to, op, ts) SELECT _id, name, CURRENT_TIMESTAMP, NULL, 'update', NULL FROM student WHERE _id = '2'",
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+
+		if converter.Rows != nil {
+			t.Errorf("expected no rows to be tracked, got %v", converter.Rows)
+		}
+	})
+
+	t.Run("named per namespace", func(t *testing.T) {
+		converter := chroma.NewConverter()
+		converter.Config = chroma.Config{
+			Namespaces: map[string]chroma.NamespaceConfig{
+				"history.teacher": {History: &chroma.History{Suffix: "_versions", Op: "change"}},
+			},
+		}
+
+		got := collect(t, converter, `{"op": "i", "ns": "history.teacher", "o": {"_id": "1"}}
+{"op": "i", "ns": "history.student", "o": {"_id": "1"}}`)
+
+		joined := strings.Join(got, "\n")
+
+		if !strings.Contains(joined, "INSERT INTO teacher_versions (_id, valid_from, valid_to, change, ts)") {
+			t.Errorf("expected a version of the teacher, got %q", got)
+		}
+
+		if strings.Contains(joined, "student_history") {
+			t.Errorf("expected no history of students, got %q", got)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package chroma
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// Hook rewrites what passes through a converter: Entry sees the raw oplog
+// entry before it is parsed, Handler the parsed handler and SQL every
+// statement rendered from it. Entry and Handler run on several goroutines at
+// once, each with its own entry.
+type Hook interface {
+	Entry(oplog map[string]interface{}) error
+	Handler(oplog map[string]interface{}, handler Handler) (Handler, error)
+	// SQL returns the statement to write, or "" to leave it out.
+	SQL(oplog map[string]interface{}, statement string) (string, error)
+}
+
+// HookFuncs is a Hook made of functions, any of which may be nil.
+type HookFuncs struct {
+	EntryFunc   func(oplog map[string]interface{}) error
+	HandlerFunc func(oplog map[string]interface{}, handler Handler) (Handler, error)
+	SQLFunc     func(oplog map[string]interface{}, statement string) (string, error)
+}
+
+func (h HookFuncs) Entry(oplog map[string]interface{}) error {
+	if h.EntryFunc == nil {
+		return nil
+	}
+
+	return h.EntryFunc(oplog)
+}
+
+func (h HookFuncs) Handler(oplog map[string]interface{}, handler Handler) (Handler, error) {
+	if h.HandlerFunc == nil {
+		return handler, nil
+	}
+
+	return h.HandlerFunc(oplog, handler)
+}
+
+func (h HookFuncs) SQL(oplog map[string]interface{}, statement string) (string, error) {
+	if h.SQLFunc == nil {
+		return statement, nil
+	}
+
+	return h.SQLFunc(oplog, statement)
+}
+
+// SkipEntry is returned by an Entry or Handler hook to leave an entry out of
+// the conversion.
+var SkipEntry = errors.New("skip entry")
+
+// Use adds hooks to the converter, to run after the hooks of its config and
+// those added before, in order.
+func (c *Converter) Use(hooks ...Hook) *Converter {
+	c.hooks = append(c.hooks, hooks...)
+
+	return c
+}
+
+func (c *Converter) entryHooks(oplog map[string]interface{}) error {
+	if err := c.Config.entryHook(oplog); err != nil {
+		return err
+	}
+
+	for _, hook := range c.hooks {
+		if err := hook.Entry(oplog); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Converter) handlerHooks(oplog map[string]interface{}, handler Handler) (Handler, error) {
+	var err error
+
+	for _, hook := range c.hooks {
+		handler, err = hook.Handler(oplog, handler)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return handler, nil
+}
+
+func (c *Converter) sqlHooks(oplog map[string]interface{}, statement string) (string, error) {
+	var err error
+
+	for _, hook := range c.hooks {
+		statement, err = hook.SQL(oplog, statement)
+		if err != nil || statement == "" {
+			return "", err
+		}
+	}
+
+	return statement, nil
+}
+
+// HookConfig is a common rewrite of entries that can be set up from config.
+// It applies to the fields of inserts and to the fields set and unset by
+// updates.
+type HookConfig struct {
+	// Type is one of add_columns, drop_fields or derive.
+	Type string `json:"type"`
+	// Columns are added to every insert by add_columns, with their values.
+	Columns map[string]interface{} `json:"columns"`
+	// Fields are removed by drop_fields.
+	Fields []string `json:"fields"`
+	// Column is set by derive to the value of From, or to the first group
+	// Pattern matches in it.
+	Column  string `json:"column"`
+	From    string `json:"from"`
+	Pattern string `json:"pattern"`
+}
+
+func (h HookConfig) validate() error {
+	switch h.Type {
+	case "add_columns":
+		if len(h.Columns) == 0 {
+			return fmt.Errorf("%w: add_columns hook needs columns", ConfigError)
+		}
+	case "drop_fields":
+		if len(h.Fields) == 0 {
+			return fmt.Errorf("%w: drop_fields hook needs fields", ConfigError)
+		}
+	case "derive":
+		if h.Column == "" || h.From == "" {
+			return fmt.Errorf("%w: derive hook needs a column and a field to derive it from", ConfigError)
+		}
+		if _, err := regexp.Compile(h.Pattern); err != nil {
+			return fmt.Errorf("%w: %v", ConfigError, err)
+		}
+	default:
+		return fmt.Errorf("%w: unknown hook %q", ConfigError, h.Type)
+	}
+
+	return nil
+}
+
+// entryHook applies the hooks of the config, those for every namespace
+// first, to a raw entry.
+func (c Config) entryHook(oplog map[string]interface{}) error {
+	hooks := c.Hooks
+
+	if namespace, ok := c.Namespaces[getNamespace(oplog)]; ok {
+		hooks = append(hooks[:len(hooks):len(hooks)], namespace.Hooks...)
+	}
+
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	object, _ := oplog["o"].(map[string]interface{})
+	documents := make(map[string]map[string]interface{})
+
+	switch oplog["op"] {
+	case "insert":
+		documents["insert"] = object
+	case "update":
+		diff, _ := object["diff"].(map[string]interface{})
+		if set, ok := diff["u"].(map[string]interface{}); ok {
+			documents["set"] = set
+		}
+		if unset, ok := diff["d"].(map[string]interface{}); ok {
+			documents["unset"] = unset
+		}
+	default:
+		return nil
+	}
+
+	for _, hook := range hooks {
+		for kind, document := range documents {
+			if err := hook.apply(kind, document); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// apply rewrites the fields of an insert, or those set or unset by an update.
+func (h HookConfig) apply(kind string, document map[string]interface{}) error {
+	switch h.Type {
+	case "add_columns":
+		if kind != "insert" {
+			return nil
+		}
+		for column, value := range h.Columns {
+			document[column] = value
+		}
+	case "drop_fields":
+		for _, field := range h.Fields {
+			delete(document, field)
+		}
+	case "derive":
+		value, ok := document[h.From]
+		if !ok || kind == "unset" {
+			return nil
+		}
+
+		if h.Pattern == "" {
+			document[h.Column] = value
+			return nil
+		}
+
+		pattern, err := compilePattern(h.Pattern)
+		if err != nil {
+			return err
+		}
+
+		match := pattern.FindStringSubmatch(fmt.Sprint(value))
+		switch {
+		case match == nil:
+			return nil
+		case len(match) > 1:
+			document[h.Column] = match[1]
+		default:
+			document[h.Column] = match[0]
+		}
+	}
+
+	return nil
+}
+
+// patterns caches the compiled patterns of derive hooks.
+var patterns sync.Map
+
+func compilePattern(expr string) (*regexp.Regexp, error) {
+	if pattern, ok := patterns.Load(expr); ok {
+		return pattern.(*regexp.Regexp), nil
+	}
+
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns.Store(expr, pattern)
+
+	return pattern, nil
+}
@@ -0,0 +1,106 @@
+package chroma_test
+
+import (
+	"errors"
+	chroma "github.com/Adedunmol/chroma"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHooks(t *testing.T) {
+	oplog := `{"op": "i", "ns": "hooks.student", "o": {"_id": "1", "name": "John Doe", "__v": 0}}
+{"op": "i", "ns": "hooks.student", "o": {"_id": "2", "name": "Jane Doe", "internal": true}}
+`
+
+	t.Run("chain hooks", func(t *testing.T) {
+		converter := chroma.NewConverter()
+
+		converter.Use(chroma.HookFuncs{
+			EntryFunc: func(oplog map[string]interface{}) error {
+				object := oplog["o"].(map[string]interface{})
+
+				if _, ok := object["internal"]; ok {
+					return chroma.SkipEntry
+				}
+
+				object["tenant_id"] = "acme"
+				delete(object, "__v")
+
+				return nil
+			},
+		}).Use(chroma.HookFuncs{
+			HandlerFunc: func(oplog map[string]interface{}, handler chroma.Handler) (chroma.Handler, error) {
+				if insert, ok := handler.(*chroma.Insert); ok {
+					insert.Table = "students"
+				}
+				return handler, nil
+			},
+			SQLFunc: func(oplog map[string]interface{}, statement string) (string, error) {
+				if strings.HasPrefix(statement, "CREATE SCHEMA") {
+					return "", nil
+				}
+				return statement, nil
+			},
+		})
+
+		got := collect(t, converter, oplog)
+		want := []string{
+			"CREATE TABLE IF NOT EXISTS students (\n\t _id VARCHAR(255) PRIMARY KEY,\n\t name VARCHAR(255),\n\t tenant_id VARCHAR(255)\n);",
+			"INSERT INTO students (_id, name, tenant_id) VALUES ('1', 'John Doe', 'acme');",
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+
+		if converter.Stats.Dropped != 1 {
+			t.Errorf("got %d dropped entries, want 1", converter.Stats.Dropped)
+		}
+	})
+
+	t.Run("hooks from config", func(t *testing.T) {
+		fileSystem := fstest.MapFS{"chroma.json": {Data: []byte(`{
+			"hooks": [
+				{"type": "add_columns", "columns": {"tenant_id": "acme"}},
+				{"type": "drop_fields", "fields": ["__v", "internal"]}
+			],
+			"namespaces": {
+				"hooks.student": {
+					"hooks": [{"type": "derive", "column": "last_name", "from": "name", "pattern": " (\\w+)$"}]
+				}
+			}
+		}`)}}
+
+		config, err := chroma.LoadConfig(fileSystem, "chroma.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		converter := chroma.NewConverter()
+		converter.Config = config
+
+		got := collect(t, converter, oplog+`{"op": "u", "ns": "hooks.student", "o": {"$v": 2, "diff": {"u": {"name": "Jane Roe", "internal": false}}}, "o2": {"_id": "2"}}`+"\n")
+
+		for _, want := range []string{
+			"INSERT INTO student (_id, last_name, name, tenant_id) VALUES ('1', 'Doe', 'John Doe', 'acme');",
+			"INSERT INTO student (_id, last_name, name, tenant_id) VALUES ('2', 'Doe', 'Jane Doe', 'acme');",
+			"UPDATE student SET last_name = 'Roe', name = 'Jane Roe' WHERE _id = '2'",
+		} {
+			if !strings.Contains(strings.Join(got, "\n"), want) {
+				t.Errorf("expected %q in %q", want, got)
+			}
+		}
+	})
+
+	t.Run("reject unknown hook", func(t *testing.T) {
+		fileSystem := fstest.MapFS{"chroma.json": {Data: []byte(`{"hooks": [{"type": "uppercase"}]}`)}}
+
+		_, err := chroma.LoadConfig(fileSystem, "chroma.json")
+
+		if !errors.Is(err, chroma.ConfigError) {
+			t.Errorf("got unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package chroma
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// maxLine bounds a single JSONL entry.
+const maxLine = 16 * 1024 * 1024
+
+// readInput calls fn with every entry of the input, which is either a JSON
+// array or one JSON entry per line (JSONL). With options.Follow the input is
+// tailed as JSONL until ctx is done. Entries must have an op code the
+// converter has a handler for, or one MongoDB writes.
+func (c *Converter) readInput(ctx context.Context, options Options, fn func(Entry) error) error {
+	if options.Follow {
+		return Follow(ctx, options.Input, options.Poll, c.lines(fn))
+	}
+
+	file, err := os.Open(options.Input)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", options.Input, err)
+	}
+	defer file.Close()
+
+	return c.readEntries(file, fn)
+}
+
+// readEntries calls fn with every entry of r, a JSON array or JSONL. JSONL
+// is read as it arrives, a JSON array only once it is complete.
+func (c *Converter) readEntries(r io.Reader, fn func(Entry) error) error {
+	reader := bufio.NewReader(r)
+
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+
+		if err := reader.UnreadByte(); err != nil {
+			return err
+		}
+
+		if b == '[' {
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				return err
+			}
+
+			return c.readArray(data, fn)
+		}
+
+		break
+	}
+
+	line := c.lines(fn)
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLine)
+
+	for scanner.Scan() {
+		if err := line(bytes.TrimSpace(scanner.Bytes())); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// lines returns a function parsing one JSONL line at a time into entries.
+func (c *Converter) lines(fn func(Entry) error) func([]byte) error {
+	index := 0
+
+	return func(data []byte) error {
+		if len(data) == 0 {
+			return nil
+		}
+
+		oplog, err := parseJSONMap(data, c.knows)
+		if err != nil {
+			return fmt.Errorf("entry %d: %w", index, err)
+		}
+
+		entry := Entry{Index: index, Data: oplog, Size: len(data) + 1}
+		index++
+
+		return fn(entry)
+	}
+}
+
+func (c *Converter) readArray(data []byte, fn func(Entry) error) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("error parsing oplog as JSON: %w", err)
+	}
+
+	oplogs, err := parseJSONArray(data, c.knows)
+	if err != nil {
+		return err
+	}
+
+	for idx, oplog := range oplogs {
+		if err := fn(Entry{Index: idx, Data: oplog, Size: len(raw[idx])}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isJSONArray(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
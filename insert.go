@@ -1,12 +1,14 @@
-package main
+package chroma
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
-	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
-	"sync"
+	"unicode/utf8"
 )
 
 type KeyValue struct {
@@ -14,45 +16,81 @@ type KeyValue struct {
 	Value interface{}
 }
 
+type Column struct {
+	Type       string `json:"type"`
+	Length     int    `json:"length,omitempty"`
+	NotNull    bool   `json:"not_null,omitempty"`
+	Default    string `json:"default,omitempty"`
+	PrimaryKey bool   `json:"primary_key,omitempty"`
+}
+
 type Table struct {
-	Name   string
-	Schema map[string]bool
+	Name   string            `json:"name"`
+	Schema map[string]Column `json:"columns"`
 }
 
 type Insert struct {
 	Database string
 	Table    string
 	Columns  []KeyValue
-	Diff     []string
+
+	converter *Converter
+	// softDelete, when set, adds the columns marking deleted rows to the
+	// tables the insert creates.
+	softDelete *SoftDelete
+	// history, when set, makes the insert one of a version to a history
+	// table, which has the columns of the version and no primary key.
+	history *History
+	// metadata, when set, stamps the row with its oplog entry, the values
+	// of which are in stamps.
+	metadata *Metadata
+	stamps   []KeyValue
 }
 
 var (
-	tables         = make(map[string]Table)
-	schemas        = make(map[string]bool)
 	TypeError      = errors.New("unsupported type")
 	NamespaceError = errors.New("invalid structure for namespace")
 	namespace      = regexp.MustCompile("(\\w+)\\.(\\w+)")
-	mutex          *sync.Mutex
 )
 
-func GetTable(name string) bool {
-	_, ok := tables[name]
-
-	if !ok {
-		return false
+func (c Column) String() string {
+	if c.Length > 0 {
+		return fmt.Sprintf("%s(%d)", c.Type, c.Length)
 	}
 
-	return true
+	return c.Type
 }
 
-func GetSchema(name string) bool {
-	_, ok := schemas[name]
+// definition renders the column type along with its constraints.
+func (c Column) definition() string {
+	result := c.String()
 
-	if !ok {
-		return false
+	if c.NotNull {
+		result += " NOT NULL"
+	}
+
+	if c.Default != "" {
+		result += " DEFAULT " + c.Default
 	}
 
-	return true
+	return result
+}
+
+// ResetRegistry forgets every schema and table the default converter has seen.
+func ResetRegistry() {
+	defaultConverter.Reset()
+}
+
+func GetTable(name string) bool {
+	return defaultConverter.HasTable(name)
+}
+
+func GetColumn(table, name string) (Column, bool) {
+	return defaultConverter.Column(table, name)
+}
+
+func GetSchema(name string) bool {
+	return defaultConverter.HasSchema(name)
 }
 
 func NewInsert() Insert {
@@ -60,6 +98,10 @@ func NewInsert() Insert {
 	return Insert{}
 }
 
+func (i *Insert) conv() *Converter {
+	return converterOf(i.converter)
+}
+
 func (i *Insert) Parse(data map[string]interface{}) error {
 
 	ns := getNamespace(data)
@@ -70,26 +112,50 @@ func (i *Insert) Parse(data map[string]interface{}) error {
 		return err
 	}
 
-	i.Database = match[1]
-	i.Table = match[2]
+	c := i.conv()
+
+	i.Database, i.Table = c.Config.namespace(match[1], match[2])
 	columns := i.getEntries(data)
 
-	i.Columns = columns
+	i.Columns = c.Config.columns(ns, columns)
+	i.softDelete, _ = c.Config.softDelete(ns)
+
+	if metadata, ok := c.Config.metadata(ns); ok {
+		i.metadata, i.stamps = metadata, metadata.stamp(data)
+	}
 
 	return nil
 }
 
+// String renders the statements of the insert, or nothing if its table
+// cannot be set up; Statements reports why.
 func (i *Insert) String() string {
+	statements, err := i.Statements()
+	if err != nil {
+		return ""
+	}
 
-	preStatements := i.prependStatements()
+	return strings.Join(statements, "\n")
+}
+
+// Statements returns the DDL the insert needs followed by the insert itself.
+func (i *Insert) Statements() ([]string, error) {
+	result, err := i.prependStatements()
+	if err != nil {
+		return nil, err
+	}
 
 	var columns []string
 	var values []string
 
 	for _, entry := range i.Columns {
 		columns = append(columns, entry.Key)
-		values = append(values, fmt.Sprintf("%v", entry.Value))
+		values = append(values, literal(entry.Value))
+	}
 
+	for _, stamp := range i.stamps {
+		columns = append(columns, stamp.Key)
+		values = append(values, stamp.Value.(string))
 	}
 
 	columnsStr := strings.Join(columns, ", ")
@@ -97,47 +163,39 @@ func (i *Insert) String() string {
 
 	insertStr := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", i.Table, columnsStr, valuesStr)
 
-	result := strings.Join(preStatements, "") + insertStr
+	result = append(result, insertStr)
 
-	return result
+	return result, nil
 }
 
-func (i *Insert) prependStatements() []string {
+func (i *Insert) prependStatements() ([]string, error) {
 	var preStatements []string
 
-	_, ok := schemas[i.Database]
-
-	if !ok {
-		schemaStr := i.CreateSchema()
+	schemaStr := i.CreateSchema()
 
-		preStatements = append(preStatements, schemaStr+"\n")
+	if schemaStr != "" {
+		preStatements = append(preStatements, schemaStr)
 	}
 
-	table, ok := tables[i.Table]
-
-	if !ok {
-		createTableStr, err := i.CreateTable()
-		if err != nil {
-			panic(err)
-		}
-		preStatements = append(preStatements, createTableStr+"\n")
+	createTableStr, err := i.CreateTable()
+	if err != nil {
+		return nil, fmt.Errorf("could not create table(%s): %w", i.Table, err)
 	}
 
-	if len(table.Schema) != len(i.Columns) {
-		diff := i.getDifference(i.Columns)
-		diffStr, err := i.assembleColumns(diff)
-		if err != nil {
-			panic(fmt.Errorf("could not assemble columns to alter table(%s): %w", i.Table, err))
-		}
+	if createTableStr != "" {
+		preStatements = append(preStatements, createTableStr)
+	}
 
-		if len(diffStr) != 0 {
-			alterStr := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", i.Table, strings.Join(i.Diff, " ")) + "\n"
+	alterStrs, err := i.AlterTable()
+	if err != nil {
+		return nil, fmt.Errorf("could not assemble columns to alter table(%s): %w", i.Table, err)
+	}
 
-			preStatements = append(preStatements, alterStr+"\n")
-		}
+	for _, alterStr := range alterStrs {
+		preStatements = append(preStatements, alterStr)
 	}
 
-	return preStatements
+	return preStatements, nil
 }
 
 func extractNamespace(ns string) ([]string, error) {
@@ -160,11 +218,27 @@ func (i *Insert) getEntries(data map[string]interface{}) []KeyValue {
 		return result
 	}
 
-	for key, value := range object.(map[string]interface{}) {
+	return sortedEntries(object.(map[string]interface{}))
+}
+
+// sortedEntries lists the fields of a document with _id first and the rest
+// by name, so that generated columns come out in a stable order.
+func sortedEntries(object map[string]interface{}) []KeyValue {
+	var result []KeyValue
+
+	for key, value := range object {
 		data := KeyValue{Key: key, Value: value}
 		result = append(result, data)
 	}
 
+	sort.Slice(result, func(a, b int) bool {
+		if result[a].Key == "_id" || result[b].Key == "_id" {
+			return result[a].Key == "_id"
+		}
+
+		return result[a].Key < result[b].Key
+	})
+
 	return result
 }
 
@@ -179,112 +253,349 @@ func getNamespace(data map[string]interface{}) string {
 }
 
 func (i *Insert) CreateSchema() string {
+	c := i.conv()
 
-	mutex.Lock()
-	{
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-		_, ok := schemas[i.Database]
+	_, ok := c.schemas[i.Database]
 
-		if !ok {
-			schemas[i.Database] = true
+	if ok {
+		return ""
+	}
+
+	c.schemas[i.Database] = true
+
+	schemaStr := fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s;", i.Database)
+
+	return schemaStr
+}
 
-			schemaStr := fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s;", i.Database)
+func (i *Insert) CreateTable() (string, error) {
+	c := i.conv()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, ok := c.tables[i.Table]
+
+	if ok {
+		return "", nil
+	}
 
-			return schemaStr
+	columns, schema, err := i.assembleColumns(i.Columns)
+	if err != nil {
+		return "", err
+	}
+
+	c.tables[i.Table] = Table{Name: i.Table, Schema: schema}
+
+	extra, definitions := i.extraColumns(c.Dialect)
+
+	for _, name := range extra {
+		if _, ok := schema[name]; ok {
+			continue
 		}
+
+		schema[name] = definitions[name]
+		columns = append(columns, strings.Join([]string{"\t", name, definitions[name].definition()}, " "))
 	}
-	mutex.Unlock()
 
-	return ""
+	tableStr := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n", i.Table)
+	columnsStr := strings.Join(columns, ",\n")
+
+	tableStr += columnsStr + "\n"
+
+	tableStr += ");"
+
+	return tableStr, nil
 }
 
-func (i *Insert) CreateTable() (string, error) {
+// AlterTable adds the columns the table has not seen yet and widens string
+// columns whose declared length is shorter than the incoming value.
+func (i *Insert) AlterTable() ([]string, error) {
+	var result []string
 
-	mutex.Lock()
-	{
+	c := i.conv()
 
-		_, ok := tables[i.Table]
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	table, ok := c.tables[i.Table]
+
+	if !ok {
+		return result, fmt.Errorf("no table: %s", i.Table)
+	}
+
+	result, err := i.alterColumns(table, i.Columns)
+	if err != nil {
+		return result, err
+	}
+
+	extra, definitions := i.extraColumns(c.Dialect)
+	result = append(result, c.addColumns(i.Table, extra, definitions)...)
+
+	return result, nil
+}
+
+// alterColumns adds the fields the table has no column for yet and widens
+// string columns that are too short for their values, returning the DDL.
+// The caller holds the registry lock.
+func (i *Insert) alterColumns(table Table, fields []KeyValue) ([]string, error) {
+	var result []string
+
+	c := i.conv()
+
+	for _, entry := range fields {
+		column, err := i.columnType(entry.Key, entry.Value)
+		if err != nil {
+			return result, fmt.Errorf("%w for column %s", err, entry.Key)
+		}
+
+		existing, ok := table.Schema[entry.Key]
 
 		if !ok {
+			table.Schema[entry.Key] = column
+			result = append(result, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", i.Table, entry.Key, column.definition()))
+			continue
+		}
 
-			tables[i.Table] = Table{Name: i.Table, Schema: make(map[string]bool)}
+		if widened, ok := c.Dialect.widen(existing, entry.Value); ok {
+			table.Schema[entry.Key] = widened
+			result = append(result, c.Dialect.modifyColumn(i.Table, entry.Key, widened))
+		}
+	}
 
-			for _, column := range i.Columns {
-				tables[i.Table].Schema[column.Key] = true
-			}
+	return result, nil
+}
 
-			tableStr := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n", i.Table)
-			columns, err := i.assembleColumns(i.Columns)
-			if err != nil {
-				return "", err
-			}
-			columnsStr := strings.Join(columns, "\n")
+// extraColumns lists the columns the table of the insert has besides the
+// fields of its rows.
+func (i *Insert) extraColumns(d Dialect) ([]string, map[string]Column) {
+	return extraColumns(d, i.history, i.softDelete, i.metadata)
+}
 
-			tableStr += columnsStr + "\n"
+// extraColumns lists the columns a table has besides the fields of its rows:
+// those of the versions in a history table, those marking soft deletes and
+// those stamping rows with their oplog entry.
+func extraColumns(d Dialect, history *History, soft *SoftDelete, metadata *Metadata) ([]string, map[string]Column) {
+	var names []string
+	definitions := make(map[string]Column)
+
+	if history != nil {
+		for _, name := range history.columns() {
+			names = append(names, name)
+			definitions[name] = history.column(d, name)
+		}
+	}
 
-			tableStr += ");"
+	if soft != nil {
+		for _, name := range soft.columns() {
+			names = append(names, name)
+			definitions[name] = soft.column(d, name)
+		}
+	}
 
-			return tableStr, nil
+	if metadata != nil {
+		for _, name := range metadata.columns() {
+			names = append(names, name)
+			definitions[name] = metadata.column(d, name)
 		}
 	}
-	mutex.Unlock()
 
-	return "", nil
+	return names, definitions
 }
 
-func (i *Insert) assembleColumns(columns []KeyValue) ([]string, error) {
+// addColumns adds the columns a registered table lacks, returning the DDL.
+// Tables the registry does not know are left alone. The caller holds the
+// registry lock.
+func (c *Converter) addColumns(name string, columns []string, definitions map[string]Column) []string {
 	var result []string
 
-	for idx, entry := range columns {
+	table, ok := c.tables[name]
+	if !ok {
+		return result
+	}
+
+	for _, column := range columns {
+		if _, ok := table.Schema[column]; ok {
+			continue
+		}
+
+		table.Schema[column] = definitions[column]
+		result = append(result, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", name, column, definitions[column].definition()))
+	}
+
+	return result
+}
+
+// Conflicts lists the columns whose registered type is of a different kind
+// than the incoming value, such as a string arriving in a FLOAT column.
+func (i *Insert) Conflicts() []Conflict {
+	var result []Conflict
+
+	c := i.conv()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	table, ok := c.tables[i.Table]
+
+	if !ok {
+		return result
+	}
+
+	for _, entry := range i.Columns {
+		existing, ok := table.Schema[entry.Key]
+		if !ok {
+			continue
+		}
+
+		incoming, err := i.columnType(entry.Key, entry.Value)
+		if err != nil || c.Dialect.typeFamily(existing) == c.Dialect.typeFamily(incoming) {
+			continue
+		}
+
+		result = append(result, Conflict{Table: i.Table, Column: entry.Key, Existing: existing, Incoming: incoming})
+	}
+
+	return result
+}
+
+func (d Dialect) typeFamily(column Column) string {
+	if column.Type == "VARCHAR" || column.Type == d.TextType {
+		return "string"
+	}
+
+	return column.Type
+}
+
+func (i *Insert) assembleColumns(columns []KeyValue) ([]string, map[string]Column, error) {
+	var result []string
+	schema := make(map[string]Column)
+
+	for _, entry := range columns {
 		var colEntry []string
 
 		colEntry = append(colEntry, "\t")
 		colEntry = append(colEntry, entry.Key)
 
-		switch reflect.TypeOf(entry.Value).Kind() {
-		case reflect.String:
-			colEntry = append(colEntry, "VARCHAR(255)")
-			break
-		case reflect.Int:
-			colEntry = append(colEntry, "BIGINT")
-		case reflect.Float64:
-			colEntry = append(colEntry, "FLOAT")
-		case reflect.Bool:
-			colEntry = append(colEntry, "BOOLEAN")
-		default:
-			return result, TypeError
+		column, err := i.columnType(entry.Key, entry.Value)
+		if err != nil {
+			return result, schema, fmt.Errorf("%w for column %s", err, entry.Key)
 		}
 
-		if entry.Key == "_id" {
+		column.PrimaryKey = entry.Key == "_id" && i.history == nil
+
+		schema[entry.Key] = column
+		colEntry = append(colEntry, column.definition())
+
+		if column.PrimaryKey {
 			colEntry = append(colEntry, "PRIMARY KEY")
 		}
 
 		col := strings.Join(colEntry, " ")
 
-		if idx != len(i.Columns)-1 {
-			col += ","
-		}
-
 		result = append(result, col)
 	}
 
-	return result, nil
+	return result, schema, nil
 }
 
-func (i *Insert) getDifference(columns []KeyValue) []KeyValue {
-	var result []KeyValue
+// columnType resolves the column for a value, preferring the configured
+// column override, then the configured type mapping, then the dialect.
+func (i *Insert) columnType(key string, value interface{}) (Column, error) {
+	ns := i.Database + "." + i.Table
+	c := i.conv()
 
-	table, ok := tables[i.Table]
+	if i.history != nil {
+		// the columns of a history table are configured on its table
+		ns = strings.TrimSuffix(ns, i.history.Suffix)
+	}
 
-	if !ok {
-		panic(fmt.Sprintf("no table: %s", i.Table))
+	override, hasOverride := c.Config.column(ns, key)
+
+	column, err := c.Dialect.columnType(value)
+
+	if sqlType, ok := c.Config.Types[mongoType(value)]; ok {
+		column, err = Column{Type: sqlType}, nil
 	}
-	for _, entry := range columns {
-		if _, ok := table.Schema[entry.Key]; !ok {
-			data := KeyValue{Key: entry.Key, Value: entry.Value}
-			result = append(result, data)
+
+	if hasOverride {
+		if override.Type != "" {
+			column, err = Column{Type: override.Type}, nil
+		}
+
+		column.NotNull = override.NotNull
+
+		if override.Default != nil {
+			column.Default = literal(override.Default)
 		}
 	}
 
-	return result
+	return column, err
+}
+
+// columnType is the column the dialect uses for a value by default.
+func (d Dialect) columnType(value interface{}) (Column, error) {
+	switch v := value.(type) {
+	case string:
+		return d.stringColumn(utf8.RuneCountInString(v)), nil
+	case int, int64:
+		return Column{Type: "BIGINT"}, nil
+	case float64:
+		return Column{Type: "FLOAT"}, nil
+	case bool:
+		return Column{Type: "BOOLEAN"}, nil
+	default:
+		return Column{}, TypeError
+	}
+}
+
+// literal renders a value as a SQL literal.
+func literal(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "NULL"
+		}
+		return literal(string(data))
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// widen reports the column needed to hold a string value that is longer
+// than the existing VARCHAR declaration allows.
+func (d Dialect) widen(existing Column, value interface{}) (Column, bool) {
+	str, ok := value.(string)
+
+	if !ok || existing.Type != "VARCHAR" {
+		return existing, false
+	}
+
+	length := utf8.RuneCountInString(str)
+
+	if length <= existing.Length {
+		return existing, false
+	}
+
+	widened := d.stringColumn(length)
+	widened.NotNull = existing.NotNull
+	widened.Default = existing.Default
+	widened.PrimaryKey = existing.PrimaryKey
+
+	return widened, true
 }
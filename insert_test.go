@@ -1,4 +1,4 @@
-package main_test
+package chroma_test
 
 import (
 	chroma "github.com/Adedunmol/chroma"
@@ -47,10 +47,10 @@ func TestParseInsert(t *testing.T) {
 		Table:    "student",
 		Columns: []chroma.KeyValue{
 			{Key: "_id", Value: "635b79e231d82a8ab1de863b"},
+			{Key: "date_of_birth", Value: "2000-01-30"},
+			{Key: "is_graduated", Value: false},
 			{Key: "name", Value: "John Doe"},
 			{Key: "roll_no", Value: float64(51)},
-			{Key: "is_graduated", Value: false},
-			{Key: "date_of_birth", Value: "2000-01-30"},
 		},
 	}
 
@@ -60,6 +60,8 @@ func TestParseInsert(t *testing.T) {
 }
 
 func TestStringInsert(t *testing.T) {
+	chroma.ResetRegistry()
+
 	oplog := []byte(`{
 		"op": "i",
 		"ns": "test.student",
@@ -96,6 +98,7 @@ func TestStringInsert(t *testing.T) {
 func TestCreateTable(t *testing.T) {
 
 	t.Run("create table", func(t *testing.T) {
+		chroma.ResetRegistry()
 
 		oplog := []byte(`{
 		"op": "i",
@@ -165,6 +168,7 @@ func TestCreateTable(t *testing.T) {
 
 func TestCreateSchema(t *testing.T) {
 	t.Run("create schema", func(t *testing.T) {
+		chroma.ResetRegistry()
 		oplog := []byte(`{
 		"op": "i",
 		"ns": "test.student",
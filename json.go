@@ -1,27 +1,45 @@
-package main
+package chroma
 
 import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 )
 
 var UnknownOp = errors.New("unknown op")
 
+// Oplog is an oplog entry. Its metadata may be in relaxed or extended JSON,
+// and is left empty when missing.
 type Oplog struct {
 	Op        string                 `json:"op"`
 	Namespace string                 `json:"ns"`
 	Object    map[string]interface{} `json:"o"`
+	// Object2 identifies the document an update changes.
+	Object2 map[string]interface{} `json:"o2,omitempty"`
+	// Timestamp orders the entry in the oplog and Wall is the wall clock
+	// time of the operation.
+	Timestamp *Timestamp `json:"ts,omitempty"`
+	Wall      *time.Time `json:"wall,omitempty"`
+	// Term is the election term of the primary that wrote the entry.
+	Term *int64 `json:"t,omitempty"`
+	// SessionID and TxnNumber identify the transaction the entry is part of.
+	SessionID map[string]interface{} `json:"lsid,omitempty"`
+	TxnNumber *int64                 `json:"txnNumber,omitempty"`
+	// UUID identifies the collection.
+	UUID interface{} `json:"ui,omitempty"`
 }
 
 func ParseJSON(oplog []byte) (Oplog, error) {
-	var result Oplog
-	err := json.Unmarshal(oplog, &result)
+	var data map[string]interface{}
+	err := json.Unmarshal(oplog, &data)
 
 	if err != nil {
-		return result, fmt.Errorf("error parsing oplog as JSON: %w", err)
+		return Oplog{}, fmt.Errorf("error parsing oplog as JSON: %w", err)
 	}
 
+	result := NewOplog(data)
+
 	switch result.Op {
 	case "i":
 		result.Op = "insert"
@@ -29,6 +47,15 @@ func ParseJSON(oplog []byte) (Oplog, error) {
 	case "u":
 		result.Op = "update"
 		break
+	case "d":
+		result.Op = "delete"
+		break
+	case "c":
+		result.Op = "command"
+		break
+	case "n":
+		result.Op = "noop"
+		break
 	default:
 		return result, fmt.Errorf("%w: %s", UnknownOp, result.Op)
 	}
@@ -36,7 +63,44 @@ func ParseJSON(oplog []byte) (Oplog, error) {
 	return result, nil
 }
 
+// NewOplog reads an entry parsed into a map, as by ParseJSONMap.
+func NewOplog(data map[string]interface{}) Oplog {
+	var result Oplog
+
+	result.Op, _ = data["op"].(string)
+	result.Namespace, _ = data["ns"].(string)
+	result.Object, _ = data["o"].(map[string]interface{})
+	result.Object2, _ = data["o2"].(map[string]interface{})
+	result.SessionID, _ = data["lsid"].(map[string]interface{})
+	result.UUID = data["ui"]
+
+	if ts, ok := entryTimestamp(data); ok {
+		result.Timestamp = &ts
+	}
+
+	if wall, ok := entryWall(data); ok {
+		result.Wall = &wall
+	}
+
+	if term, ok := ParseInt(data["t"]); ok {
+		result.Term = &term
+	}
+
+	if txnNumber, ok := ParseInt(data["txnNumber"]); ok {
+		result.TxnNumber = &txnNumber
+	}
+
+	return result
+}
+
+// ParseJSONMap parses one oplog entry, whose op code must be one MongoDB
+// writes or one RegisterHandler knows.
 func ParseJSONMap(oplog []byte) (map[string]interface{}, error) {
+	return parseJSONMap(oplog, registered)
+}
+
+// parseJSONMap parses one oplog entry, whose op code known accepts.
+func parseJSONMap(oplog []byte, known func(op string) bool) (map[string]interface{}, error) {
 	var dest map[string]interface{}
 	err := json.Unmarshal(oplog, &dest)
 
@@ -48,7 +112,7 @@ func ParseJSONMap(oplog []byte) (map[string]interface{}, error) {
 		return map[string]interface{}{}, fmt.Errorf("wrong structure")
 	}
 
-	err = validateOperation(dest)
+	err = validateOperation(dest, known)
 
 	if err != nil {
 		return map[string]interface{}{}, fmt.Errorf("error validating oplog as JSON: %w", err)
@@ -58,6 +122,10 @@ func ParseJSONMap(oplog []byte) (map[string]interface{}, error) {
 }
 
 func ParseJSONArray(oplog []byte) ([]map[string]interface{}, error) {
+	return parseJSONArray(oplog, registered)
+}
+
+func parseJSONArray(oplog []byte, known func(op string) bool) ([]map[string]interface{}, error) {
 	var dest []map[string]interface{}
 	err := json.Unmarshal(oplog, &dest)
 
@@ -65,17 +133,17 @@ func ParseJSONArray(oplog []byte) ([]map[string]interface{}, error) {
 		return []map[string]interface{}{}, fmt.Errorf("error parsing oplog as JSON: %w", err)
 	}
 
-	for _, oplog := range dest {
-		err = validateOperation(oplog)
+	for idx, oplog := range dest {
+		err = validateOperation(oplog, known)
 		if err != nil {
-			return []map[string]interface{}{}, fmt.Errorf("error validating oplog as JSON: %w for %v", err, oplog)
+			return []map[string]interface{}{}, fmt.Errorf("error validating oplog as JSON: %w at entry %d", err, idx)
 		}
 	}
 
 	return dest, nil
 }
 
-func validateOperation(oplog map[string]interface{}) error {
+func validateOperation(oplog map[string]interface{}, known func(op string) bool) error {
 
 	switch oplog["op"] {
 	case "i":
@@ -87,8 +155,17 @@ func validateOperation(oplog map[string]interface{}) error {
 	case "d":
 		oplog["op"] = "delete"
 		break
+	case "c":
+		oplog["op"] = "command"
+		break
+	case "n":
+		oplog["op"] = "noop"
+		break
 	default:
-		return fmt.Errorf("%w: %s", UnknownOp, oplog["op"])
+		op, _ := oplog["op"].(string)
+		if !known(op) {
+			return fmt.Errorf("%w: %s", UnknownOp, oplog["op"])
+		}
 	}
 
 	return nil
@@ -1,10 +1,11 @@
-package main_test
+package chroma_test
 
 import (
 	"errors"
 	chroma "github.com/Adedunmol/chroma"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestParseJSON(t *testing.T) {
@@ -77,6 +78,18 @@ func TestParseJSON(t *testing.T) {
 		assertObjectEqual(t, got.Object, want.Object)
 	})
 
+	t.Run("check parsing of delete", func(t *testing.T) {
+		oplog := []byte(`{"op": "d", "ns": "test.student", "o": {"_id": "635b79e231d82a8ab1de863b"}}`)
+
+		got, err := chroma.ParseJSON(oplog)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertEqual(t, got.Op, "delete")
+		assertEqual(t, got.Namespace, "test.student")
+	})
+
 	t.Run("check parsing of unknown operation", func(t *testing.T) {
 		oplog := []byte(`{
 		"op": "g",
@@ -279,3 +292,73 @@ func assertObjectEqual(t *testing.T, got, want map[string]interface{}) {
 		t.Errorf("got %v, want %v", got, want)
 	}
 }
+
+func TestNewOplog(t *testing.T) {
+	t.Run("reads the metadata of an entry", func(t *testing.T) {
+		oplog := []byte(`{
+		"op": "u",
+		"ns": "test.student",
+		"o": {"$v": 2, "diff": {"u": {"name": "Jane"}}},
+		"o2": {"_id": "1"},
+		"ts": {"$timestamp": {"t": 1700000000, "i": 3}},
+		"t": {"$numberLong": "7"},
+		"wall": {"$date": "2023-11-14T22:13:20.123Z"},
+		"lsid": {"id": {"$binary": {"base64": "AAAA", "subType": "04"}}},
+		"txnNumber": {"$numberLong": "12"},
+		"ui": {"$binary": {"base64": "BBBB", "subType": "04"}}
+	}`)
+
+		got, err := chroma.ParseJSON(oplog)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := (chroma.Timestamp{T: 1700000000, I: 3}); got.Timestamp == nil || *got.Timestamp != want {
+			t.Errorf("got ts %v, want %v", got.Timestamp, want)
+		}
+
+		if want := time.Date(2023, 11, 14, 22, 13, 20, 123000000, time.UTC); got.Wall == nil || !got.Wall.Equal(want) {
+			t.Errorf("got wall %v, want %v", got.Wall, want)
+		}
+
+		if got.Term == nil || *got.Term != 7 {
+			t.Errorf("got t %v, want 7", got.Term)
+		}
+
+		if got.TxnNumber == nil || *got.TxnNumber != 12 {
+			t.Errorf("got txnNumber %v, want 12", got.TxnNumber)
+		}
+
+		if got.SessionID == nil || got.UUID == nil {
+			t.Errorf("got lsid %v and ui %v, want both", got.SessionID, got.UUID)
+		}
+
+		assertObjectEqual(t, got.Object2, map[string]interface{}{"_id": "1"})
+	})
+
+	t.Run("reads relaxed JSON", func(t *testing.T) {
+		got := chroma.NewOplog(map[string]interface{}{
+			"op":        "i",
+			"ns":        "test.student",
+			"wall":      float64(1700000000000),
+			"t":         float64(2),
+			"txnNumber": "4",
+		})
+
+		if want := time.UnixMilli(1700000000000); got.Wall == nil || !got.Wall.Equal(want) {
+			t.Errorf("got wall %v, want %v", got.Wall, want)
+		}
+
+		if got.Term == nil || *got.Term != 2 || got.TxnNumber == nil || *got.TxnNumber != 4 {
+			t.Errorf("got t %v and txnNumber %v, want 2 and 4", got.Term, got.TxnNumber)
+		}
+	})
+
+	t.Run("leaves missing metadata empty", func(t *testing.T) {
+		got := chroma.NewOplog(map[string]interface{}{"op": "i", "ns": "test.student"})
+
+		if got.Timestamp != nil || got.Wall != nil || got.Term != nil || got.TxnNumber != nil {
+			t.Errorf("got %+v, want no metadata", got)
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package chroma
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// SchemaLock is the approved schema. Entries that would add a table or column
+// it does not list, or change a column's type, are violations.
+type SchemaLock struct {
+	Tables map[string]Table
+	// Strict fails the conversion on the first violation, unless DeadLetter
+	// is set, in which case offending entries are written there and skipped.
+	Strict     bool
+	DeadLetter io.Writer
+	Violations []Violation
+}
+
+// Violation is a schema change an entry would make without approval.
+type Violation struct {
+	Entry  int
+	Table  string
+	Column string
+	// Reason is one of table, column or type.
+	Reason   string
+	Approved Column
+	Incoming Column
+}
+
+var SchemaViolation = errors.New("schema violation")
+
+func (v Violation) String() string {
+	switch v.Reason {
+	case "table":
+		return fmt.Sprintf("unapproved table %s", v.Table)
+	case "column":
+		return fmt.Sprintf("unapproved column %s.%s %s", v.Table, v.Column, v.Incoming)
+	default:
+		return fmt.Sprintf("type change of %s.%s from %s to %s", v.Table, v.Column, v.Approved, v.Incoming)
+	}
+}
+
+// LoadSchemaLock reads an approved schema, in the format of a state file.
+func LoadSchemaLock(name string) (*SchemaLock, error) {
+	state, err := LoadState(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SchemaLock{Tables: state.Tables}, nil
+}
+
+// SetSchemaLock sets the schema lock of the default converter.
+func SetSchemaLock(l *SchemaLock) {
+	defaultConverter.Lock = l
+}
+
+// Check lists the violations of a parsed entry, without touching the registry.
+func (l *SchemaLock) Check(entry Entry, handler Handler) []Violation {
+	var table string
+	var columns []KeyValue
+	var insert *Insert
+
+	switch h := handler.(type) {
+	case *Insert:
+		table, columns, insert = h.Table, h.Columns, h
+	case *Update:
+		// unset fields only ever become NULL
+		table, columns, insert = h.Table, h.Columns, &Insert{Database: h.Database, Table: h.Table, converter: h.converter}
+	default:
+		return nil
+	}
+
+	approved, ok := l.Tables[table]
+	if !ok {
+		return []Violation{{Entry: entry.Index, Table: table, Reason: "table"}}
+	}
+
+	var result []Violation
+
+	for _, column := range columns {
+		incoming, err := insert.columnType(column.Key, column.Value)
+		if err != nil {
+			continue
+		}
+
+		existing, ok := approved.Schema[column.Key]
+
+		if !ok {
+			result = append(result, Violation{Entry: entry.Index, Table: table, Column: column.Key, Reason: "column", Incoming: incoming})
+			continue
+		}
+
+		d := insert.conv().Dialect
+		widened, isWider := d.widen(existing, column.Value)
+
+		if d.typeFamily(existing) != d.typeFamily(incoming) || isWider {
+			if isWider {
+				incoming = widened
+			}
+			result = append(result, Violation{Entry: entry.Index, Table: table, Column: column.Key, Reason: "type", Approved: existing, Incoming: incoming})
+		}
+	}
+
+	return result
+}
+
+// admit checks an entry against the lock, reporting whether it should be
+// converted.
+func (l *SchemaLock) admit(entry Entry, handler Handler) (bool, error) {
+	violations := l.Check(entry, handler)
+
+	if len(violations) == 0 {
+		return true, nil
+	}
+
+	l.Violations = append(l.Violations, violations...)
+
+	if !l.Strict {
+		return true, nil
+	}
+
+	if l.DeadLetter == nil {
+		return false, fmt.Errorf("%w at entry %d: %s", SchemaViolation, entry.Index, violations[0])
+	}
+
+	var reasons []string
+	for _, violation := range violations {
+		reasons = append(reasons, violation.String())
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"entry": entry.Index, "reasons": reasons, "oplog": entry.Data})
+	if err != nil {
+		return false, err
+	}
+
+	_, err = l.DeadLetter.Write(append(data, '\n'))
+
+	return false, err
+}
+
+// Report prints every distinct violation with the number of entries and the
+// first entry it was seen in.
+func (l *SchemaLock) Report(w io.Writer) {
+	if len(l.Violations) == 0 {
+		return
+	}
+
+	type seen struct {
+		first int
+		count int
+	}
+
+	counts := make(map[string]*seen)
+	var order []string
+
+	for _, violation := range l.Violations {
+		key := violation.String()
+
+		if _, ok := counts[key]; !ok {
+			counts[key] = &seen{first: violation.Entry}
+			order = append(order, key)
+		}
+
+		counts[key].count++
+	}
+
+	sort.Strings(order)
+
+	fmt.Fprintln(w, "schema drift:")
+	for _, key := range order {
+		fmt.Fprintf(w, "  %s (%d entries, first at entry %d)\n", key, counts[key].count, counts[key].first)
+	}
+}
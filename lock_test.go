@@ -0,0 +1,107 @@
+package chroma_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	chroma "github.com/Adedunmol/chroma"
+	"strings"
+	"testing"
+)
+
+func TestSchemaLock(t *testing.T) {
+	oplogs := []byte(`[
+		{"op": "i", "ns": "lock.student", "o": {"_id": "1", "name": "John Doe"}},
+		{"op": "i", "ns": "lock.student", "o": {"_id": "2", "name": "Jane Doe", "nickname": "JD"}},
+		{"op": "u", "ns": "lock.student", "o": {"$v": 2, "diff": {"u": {"name": 42}}}, "o2": {"_id": "1"}},
+		{"op": "i", "ns": "lock.teacher", "o": {"_id": "3"}}
+	]`)
+
+	approved := map[string]chroma.Table{
+		"student": {
+			Name: "student",
+			Schema: map[string]chroma.Column{
+				"_id":  {Type: "VARCHAR", Length: 255},
+				"name": {Type: "VARCHAR", Length: 255},
+			},
+		},
+	}
+
+	t.Run("report drift", func(t *testing.T) {
+		chroma.ResetRegistry()
+
+		lock := &chroma.SchemaLock{Tables: approved}
+		chroma.SetSchemaLock(lock)
+		defer chroma.SetSchemaLock(nil)
+
+		var buffer bytes.Buffer
+		if err := convert(t, oplogs, chroma.NewFileSink(&buffer)); err != nil {
+			t.Fatal(err)
+		}
+
+		var report bytes.Buffer
+		lock.Report(&report)
+
+		for _, want := range []string{
+			"unapproved column student.nickname VARCHAR(255) (1 entries, first at entry 1)",
+			"type change of student.name from VARCHAR(255) to FLOAT (1 entries, first at entry 2)",
+			"unapproved table teacher (1 entries, first at entry 3)",
+		} {
+			if !strings.Contains(report.String(), want) {
+				t.Errorf("expected report to contain: %s, got %s", want, report.String())
+			}
+		}
+
+		if !strings.Contains(buffer.String(), "nickname") {
+			t.Errorf("expected drift to be converted without -strict")
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		chroma.ResetRegistry()
+
+		chroma.SetSchemaLock(&chroma.SchemaLock{Tables: approved, Strict: true})
+		defer chroma.SetSchemaLock(nil)
+
+		var buffer bytes.Buffer
+		err := convert(t, oplogs, chroma.NewFileSink(&buffer))
+
+		if !errors.Is(err, chroma.SchemaViolation) {
+			t.Errorf("got unexpected error: %v", err)
+		}
+	})
+
+	t.Run("dead letter", func(t *testing.T) {
+		chroma.ResetRegistry()
+
+		var deadLetter bytes.Buffer
+		chroma.SetSchemaLock(&chroma.SchemaLock{Tables: approved, Strict: true, DeadLetter: &deadLetter})
+		defer chroma.SetSchemaLock(nil)
+
+		var buffer bytes.Buffer
+		if err := convert(t, oplogs, chroma.NewFileSink(&buffer)); err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(deadLetter.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("got %d dead letters, want %d: %q", len(lines), 3, lines)
+		}
+
+		var first struct {
+			Entry   int
+			Reasons []string
+		}
+		if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+			t.Fatal(err)
+		}
+
+		if first.Entry != 1 {
+			t.Errorf("got entry %d, want %d", first.Entry, 1)
+		}
+
+		if strings.Contains(buffer.String(), "nickname") || strings.Contains(buffer.String(), "teacher") {
+			t.Errorf("expected offending entries to be skipped, got %s", buffer.String())
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package chroma
+
+// Metadata stamps the rows that inserts and updates write with the oplog
+// entry they came from, so that they can be loaded and ordered
+// incrementally downstream.
+type Metadata struct {
+	// Ts, Wall and Op name the columns holding the ts of the entry, its wall
+	// clock time and its operation. They default to _oplog_ts, _oplog_wall
+	// and _oplog_op.
+	Ts   string `json:"ts"`
+	Wall string `json:"wall"`
+	Op   string `json:"op"`
+}
+
+// metadata returns how the rows of a namespace are stamped, if they are.
+func (c Config) metadata(ns string) (*Metadata, bool) {
+	result, ok := override(c.Metadata, c.Namespaces[ns].Metadata)
+	if !ok {
+		return nil, false
+	}
+
+	defaults(map[*string]string{
+		&result.Ts:   "_oplog_ts",
+		&result.Wall: "_oplog_wall",
+		&result.Op:   "_oplog_op",
+	})
+
+	return result, true
+}
+
+func (m Metadata) columns() []string {
+	return []string{m.Ts, m.Wall, m.Op}
+}
+
+func (m Metadata) column(d Dialect, name string) Column {
+	switch name {
+	case m.Ts:
+		return tsColumn()
+	case m.Wall:
+		return d.timestampColumn()
+	default:
+		return d.stringColumn(len("insert"))
+	}
+}
+
+// stamp returns the columns stamped on the row of an entry, with their
+// values rendered as SQL.
+func (m Metadata) stamp(oplog map[string]interface{}) []KeyValue {
+	return []KeyValue{
+		{Key: m.Ts, Value: timestampLiteral(oplog)},
+		{Key: m.Wall, Value: wallLiteral(oplog)},
+		{Key: m.Op, Value: literal(oplog["op"])},
+	}
+}
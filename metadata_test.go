@@ -0,0 +1,98 @@
+package chroma_test
+
+import (
+	chroma "github.com/Adedunmol/chroma"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMetadata(t *testing.T) {
+	oplogs := `{"op": "i", "ns": "metadata.student", "o": {"_id": "1", "name": "John"}, "ts": {"$timestamp": {"t": 1700000000, "i": 1}}, "wall": {"$date": "2023-11-14T22:13:20.250Z"}}
+{"op": "u", "ns": "metadata.student", "o": {"$v": 2, "diff": {"u": {"name": "Jane"}}}, "o2": {"_id": "1"}, "ts": {"$timestamp": {"t": 1700000060, "i": 2}}}
+{"op": "d", "ns": "metadata.student", "o": {"_id": "1"}}`
+
+	t.Run("stamps inserts and updates", func(t *testing.T) {
+		converter := chroma.NewConverter()
+		converter.Config = chroma.Config{Metadata: &chroma.Metadata{}}
+
+		got := collect(t, converter, oplogs)
+
+		want := []string{
+			"CREATE SCHEMA IF NOT EXISTS metadata;",
+			"CREATE TABLE IF NOT EXISTS student (\n\t _id VARCHAR(255) PRIMARY KEY,\n\t name VARCHAR(255),\n\t _oplog_ts NUMERIC(20),\n\t _oplog_wall DATETIME,\n\t _oplog_op VARCHAR(255)\n);",
+			"INSERT INTO student (_id, name, _oplog_ts, _oplog_wall, _oplog_op) VALUES ('1', 'John', 7301444403200000001, '2023-11-14 22:13:20.250', 'insert');",
+			"UPDATE student SET name = 'Jane', _oplog_ts = 7301444660898037762, _oplog_wall = NULL, _oplog_op = 'update' WHERE _id = '1'",
+			"DELETE FROM student WHERE _id = '1'",
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("adds the columns to known tables", func(t *testing.T) {
+		converter := chroma.NewConverter()
+
+		collect(t, converter, `{"op": "i", "ns": "metadata.student", "o": {"_id": "1", "name": "John"}}`)
+
+		converter.Config = chroma.Config{Metadata: &chroma.Metadata{}}
+
+		got := collect(t, converter, `{"op": "u", "ns": "metadata.student", "o": {"$v": 2, "diff": {"d": {"name": false}}}, "o2": {"_id": "1"}}`)
+
+		want := []string{
+			"ALTER TABLE student ADD COLUMN _oplog_ts NUMERIC(20);",
+			"ALTER TABLE student ADD COLUMN _oplog_wall DATETIME;",
+			"ALTER TABLE student ADD COLUMN _oplog_op VARCHAR(255);",
+			"UPDATE student SET name = NULL, _oplog_ts = NULL, _oplog_wall = NULL, _oplog_op = 'update' WHERE _id = '1'",
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("stamps a ts past a signed BIGINT", func(t *testing.T) {
+		converter := chroma.NewConverter()
+		converter.Config = chroma.Config{Metadata: &chroma.Metadata{}}
+
+		got := collect(t, converter, `{"op": "i", "ns": "metadata.student", "o": {"_id": "1"}, "ts": {"$timestamp": {"t": 2200000000, "i": 1}}}`)
+
+		if want := "INSERT INTO student (_id, _oplog_ts, _oplog_wall, _oplog_op) VALUES ('1', 9448928051200000001, NULL, 'insert');"; got[len(got)-1] != want {
+			t.Errorf("got %q, want %q", got[len(got)-1], want)
+		}
+	})
+
+	t.Run("stamps soft deletes", func(t *testing.T) {
+		converter := chroma.NewConverter()
+		converter.Config = chroma.Config{Metadata: &chroma.Metadata{}, SoftDelete: &chroma.SoftDelete{}}
+
+		got := collect(t, converter, oplogs)
+
+		if want := "UPDATE student SET deleted_at = CURRENT_TIMESTAMP, is_deleted = TRUE, _oplog_ts = NULL, _oplog_wall = NULL, _oplog_op = 'delete' WHERE _id = '1'"; got[len(got)-1] != want {
+			t.Errorf("got %q, want %q", got[len(got)-1], want)
+		}
+	})
+
+	t.Run("named per namespace", func(t *testing.T) {
+		converter := chroma.NewConverter()
+		converter.Config = chroma.Config{
+			Namespaces: map[string]chroma.NamespaceConfig{
+				"metadata.teacher": {Metadata: &chroma.Metadata{Ts: "synced_ts"}},
+			},
+		}
+
+		got := collect(t, converter, `{"op": "i", "ns": "metadata.teacher", "o": {"_id": "1"}}
+{"op": "i", "ns": "metadata.student", "o": {"_id": "1"}}`)
+
+		joined := strings.Join(got, "\n")
+
+		if !strings.Contains(joined, "INSERT INTO teacher (_id, synced_ts, _oplog_wall, _oplog_op) VALUES ('1', NULL, NULL, 'insert');") {
+			t.Errorf("expected a stamped teacher, got %q", got)
+		}
+
+		if !strings.Contains(joined, "INSERT INTO student (_id) VALUES ('1');") {
+			t.Errorf("expected an unstamped student, got %q", got)
+		}
+	})
+}
@@ -0,0 +1,298 @@
+package chroma
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Conflict is a value whose type does not fit the column it lands in.
+type Conflict struct {
+	Table    string
+	Column   string
+	Existing Column
+	Incoming Column
+	Entry    int
+}
+
+// TablePlan collects the schema changes a conversion would make to a table.
+type TablePlan struct {
+	Name       string
+	Columns    map[string]Column
+	NewColumns []string
+	Statements []string
+	Conflicts  []Conflict
+}
+
+// Plan is the DDL a conversion would emit, without any of its DML.
+type Plan struct {
+	Schemas []string
+	Tables  []*TablePlan
+	byName  map[string]*TablePlan
+}
+
+func NewPlan() *Plan {
+	return &Plan{byName: make(map[string]*TablePlan)}
+}
+
+// Add records the schema changes of an entry against the registry, updating
+// the registry as conversion would.
+func (p *Plan) Add(entry Entry, handler Handler) error {
+	if err := p.add(entry, handler); err != nil {
+		return err
+	}
+
+	return p.addHistory(entry, handler)
+}
+
+func (p *Plan) add(entry Entry, handler Handler) error {
+	switch h := handler.(type) {
+	case *Delete:
+		if h.softDelete != nil {
+			converter := converterOf(h.converter)
+			columns, _ := extraColumns(converter.Dialect, nil, h.softDelete, h.metadata)
+			return p.addColumns(h.Table, converter, columns, h.ddl)
+		}
+		return nil
+	case *Update:
+		converter := converterOf(h.converter)
+
+		var columns []string
+		for _, column := range h.Columns {
+			columns = append(columns, column.Key)
+		}
+
+		if h.metadata != nil {
+			extra, _ := extraColumns(converter.Dialect, nil, nil, h.metadata)
+			columns = append(columns, extra...)
+		}
+
+		return p.addColumns(h.Table, converter, columns, h.ddl)
+	}
+
+	insert, ok := handler.(*Insert)
+	if !ok {
+		return nil
+	}
+
+	table := p.table(insert.Table)
+
+	for _, conflict := range insert.Conflicts() {
+		conflict.Entry = entry.Index
+		table.Conflicts = append(table.Conflicts, conflict)
+	}
+
+	converter := insert.conv()
+
+	var names []string
+	for _, column := range insert.Columns {
+		names = append(names, column.Key)
+	}
+
+	extra, _ := insert.extraColumns(converter.Dialect)
+	names = append(names, extra...)
+
+	existing := make(map[string]bool)
+	for _, name := range names {
+		if _, ok := converter.Column(insert.Table, name); ok {
+			existing[name] = true
+		}
+	}
+
+	statements, err := insert.prependStatements()
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range statements {
+		if strings.HasPrefix(statement, "CREATE SCHEMA") {
+			p.Schemas = append(p.Schemas, statement)
+			continue
+		}
+
+		table.Statements = append(table.Statements, statement)
+	}
+
+	for _, name := range names {
+		definition, ok := converter.Column(insert.Table, name)
+		if !ok {
+			continue
+		}
+
+		if !existing[name] {
+			table.NewColumns = append(table.NewColumns, name)
+		}
+
+		table.Columns[name] = definition
+	}
+
+	return nil
+}
+
+// addHistory records the columns the history table of an entry's table
+// takes from it, as conversion would.
+func (p *Plan) addHistory(entry Entry, handler Handler) error {
+	table, converter, ok := target(handler)
+	if !ok {
+		return nil
+	}
+
+	ns := getNamespace(entry.Data)
+
+	history, ok := converter.Config.history(ns)
+	if !ok {
+		return nil
+	}
+
+	columns, ok := converter.versionColumns(ns, table)
+	if !ok {
+		return nil
+	}
+
+	names := append(append([]string{}, columns...), history.columns()...)
+
+	return p.addColumns(table+history.Suffix, converter, names, func() ([]string, error) {
+		return converter.mirror(table, history, columns), nil
+	})
+}
+
+// addColumns records the columns an update or a delete adds to its table,
+// or widens, by the DDL of the handler.
+func (p *Plan) addColumns(name string, converter *Converter, columns []string, ddl func() ([]string, error)) error {
+	existing := make(map[string]bool)
+	for _, column := range columns {
+		_, existing[column] = converter.Column(name, column)
+	}
+
+	statements, err := ddl()
+	if err != nil {
+		return err
+	}
+
+	if len(statements) == 0 {
+		return nil
+	}
+
+	table := p.table(name)
+	table.Statements = append(table.Statements, statements...)
+
+	for _, column := range columns {
+		definition, ok := converter.Column(name, column)
+		if !ok {
+			continue
+		}
+
+		if !existing[column] {
+			table.NewColumns = append(table.NewColumns, column)
+		}
+
+		table.Columns[column] = definition
+	}
+
+	return nil
+}
+
+func (p *Plan) table(name string) *TablePlan {
+	table, ok := p.byName[name]
+
+	if !ok {
+		table = &TablePlan{Name: name, Columns: make(map[string]Column)}
+		p.byName[name] = table
+		p.Tables = append(p.Tables, table)
+	}
+
+	return table
+}
+
+func (p *Plan) Print(w io.Writer) {
+	for _, statement := range p.Schemas {
+		fmt.Fprintln(w, statement)
+	}
+
+	for _, table := range p.Tables {
+		fmt.Fprintf(w, "\ntable %s\n", table.Name)
+
+		var names []string
+		for name := range table.Columns {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintln(w, "  columns:")
+		for _, name := range names {
+			fmt.Fprintf(w, "    %s %s\n", name, table.Columns[name].definition())
+		}
+
+		if len(table.NewColumns) > 0 {
+			fmt.Fprintf(w, "  new columns: %s\n", strings.Join(table.NewColumns, ", "))
+		}
+
+		if len(table.Conflicts) > 0 {
+			fmt.Fprintln(w, "  type conflicts:")
+			for _, conflict := range table.Conflicts {
+				fmt.Fprintf(w, "    %s is %s, got %s at entry %d\n", conflict.Column, conflict.Existing, conflict.Incoming, conflict.Entry)
+			}
+		}
+
+		if len(table.Statements) > 0 {
+			fmt.Fprintln(w, "  ddl:")
+			for _, statement := range table.Statements {
+				fmt.Fprintf(w, "    %s\n", strings.ReplaceAll(statement, "\n", "\n    "))
+			}
+		}
+	}
+}
+
+// runPlan scans the input and prints the plan for it, leaving the output
+// and any state file untouched.
+func runPlan(options Options, w io.Writer) error {
+	if options.Input == "" {
+		return NoFileFound
+	}
+
+	converter, err := setup(options)
+	if err != nil {
+		return err
+	}
+
+	plan, err := scanPlan(converter, options)
+	if err != nil {
+		return err
+	}
+
+	plan.Print(w)
+
+	return nil
+}
+
+// scanPlan reads the whole input, without following it, and plans the
+// entries the converter would convert, updating its registry as it goes.
+func scanPlan(converter *Converter, options Options) (*Plan, error) {
+	options.Follow = false
+	plan := NewPlan()
+
+	err := converter.readInput(context.Background(), options, func(entry Entry) error {
+		if !converter.Filter.Allow(entry.Data) || !converter.Window.Allow(entry.Data) || !converter.handles(entry.Data) {
+			return nil
+		}
+
+		handler, err := converter.Parse(entry.Data)
+		if errors.Is(err, SkipEntry) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("entry %d: %w", entry.Index, err)
+		}
+
+		if err := plan.Add(entry, handler); err != nil {
+			return fmt.Errorf("entry %d: %w", entry.Index, err)
+		}
+
+		return nil
+	})
+
+	return plan, err
+}
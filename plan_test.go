@@ -0,0 +1,71 @@
+package chroma_test
+
+import (
+	"bytes"
+	chroma "github.com/Adedunmol/chroma"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	chroma.ResetRegistry()
+
+	oplogs, err := chroma.ParseJSONArray([]byte(`[
+		{"op": "i", "ns": "plan.student", "o": {"_id": "1", "name": "John Doe", "roll_no": 51}},
+		{"op": "u", "ns": "plan.student", "o": {"$v": 2, "diff": {"u": {"name": "Jane Doe"}}}, "o2": {"_id": "1"}},
+		{"op": "i", "ns": "plan.student", "o": {"_id": "2", "name": "Jane Doe", "roll_no": "fifty", "is_graduated": true}}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := chroma.NewPlan()
+
+	handlers, err := chroma.SeparateOperations(oplogs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for idx, handler := range handlers {
+		if err := plan.Add(chroma.Entry{Index: idx, Data: oplogs[idx]}, handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(plan.Tables) != 1 {
+		t.Fatalf("got %d tables, want %d", len(plan.Tables), 1)
+	}
+
+	table := plan.Tables[0]
+
+	wantColumns := []string{"_id", "name", "roll_no", "is_graduated"}
+	if !reflect.DeepEqual(table.NewColumns, wantColumns) {
+		t.Errorf("got %v, want %v", table.NewColumns, wantColumns)
+	}
+
+	wantConflict := chroma.Conflict{
+		Table:    "student",
+		Column:   "roll_no",
+		Existing: chroma.Column{Type: "FLOAT"},
+		Incoming: chroma.Column{Type: "VARCHAR", Length: 255},
+		Entry:    2,
+	}
+	if len(table.Conflicts) != 1 || table.Conflicts[0] != wantConflict {
+		t.Errorf("got %#v, want %#v", table.Conflicts, wantConflict)
+	}
+
+	var buffer bytes.Buffer
+	plan.Print(&buffer)
+	got := buffer.String()
+
+	for _, want := range []string{"CREATE SCHEMA IF NOT EXISTS plan;", "CREATE TABLE IF NOT EXISTS student", "ALTER TABLE student ADD COLUMN is_graduated BOOLEAN;", "roll_no is FLOAT, got VARCHAR(255) at entry 2"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected output to contain: %s, got %s", want, got)
+		}
+	}
+
+	if strings.Contains(got, "INSERT") || strings.Contains(got, "UPDATE") {
+		t.Errorf("expected no DML, got %s", got)
+	}
+}
@@ -0,0 +1,173 @@
+package chroma
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Rows keeps the last known state of the rows a conversion touches, as the
+// source documents they were built from, so that an entry can look up what
+// its row held before the change.
+type Rows struct {
+	mutex sync.Mutex
+	rows  map[string]map[string]interface{}
+}
+
+func NewRows() *Rows {
+	return &Rows{rows: make(map[string]map[string]interface{})}
+}
+
+// Get returns the known state of the row a document identifies in a
+// namespace.
+func (r *Rows) Get(config Config, ns string, document map[string]interface{}) (map[string]interface{}, bool) {
+	id, ok := rowID(config, ns, document)
+	if !ok {
+		return nil, false
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	row, ok := r.rows[id]
+
+	return copyDocument(row), ok
+}
+
+// before returns the row an entry changes as it was before, from the
+// preImage of the entry or from the rows seen so far.
+func (r *Rows) before(config Config, oplog map[string]interface{}) (map[string]interface{}, bool) {
+	if image, ok := oplog["preImage"].(map[string]interface{}); ok {
+		return copyDocument(image), true
+	}
+
+	return r.Get(config, getNamespace(oplog), identity(oplog))
+}
+
+// after returns the row an insert or update leaves behind, if it is known.
+// Updates of rows that were never seen, and come without a preImage, leave
+// them unknown.
+func (r *Rows) after(config Config, oplog map[string]interface{}) (map[string]interface{}, bool) {
+	switch oplog["op"] {
+	case "insert":
+		object, _ := oplog["o"].(map[string]interface{})
+		return copyDocument(object), true
+	case "update":
+		row, ok := r.before(config, oplog)
+		if !ok {
+			return nil, false
+		}
+
+		object, _ := oplog["o"].(map[string]interface{})
+		diff, _ := object["diff"].(map[string]interface{})
+
+		if set, ok := diff["u"].(map[string]interface{}); ok {
+			for field, value := range set {
+				row[field] = value
+			}
+		}
+
+		if unset, ok := diff["d"].(map[string]interface{}); ok {
+			for field := range unset {
+				delete(row, field)
+			}
+		}
+
+		return row, true
+	default:
+		return nil, false
+	}
+}
+
+// apply records the change an entry makes to its row.
+func (r *Rows) apply(config Config, oplog map[string]interface{}) {
+	id, ok := rowID(config, getNamespace(oplog), identity(oplog))
+	if !ok {
+		return
+	}
+
+	if oplog["op"] == "delete" {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		delete(r.rows, id)
+		return
+	}
+
+	if row, ok := r.after(config, oplog); ok {
+		r.set(id, row)
+	}
+}
+
+func (r *Rows) set(id string, row map[string]interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.rows[id] = row
+}
+
+// identity returns the document of an entry that identifies its row: o2 for
+// updates and o otherwise.
+func identity(oplog map[string]interface{}) map[string]interface{} {
+	if oplog["op"] == "update" {
+		document, _ := oplog["o2"].(map[string]interface{})
+		return document
+	}
+
+	document, _ := oplog["o"].(map[string]interface{})
+
+	return document
+}
+
+// rowKey picks the fields of a document that identify its row: the key of
+// the namespace if the config sets one, or _id.
+func (c Config) rowKey(ns string, document map[string]interface{}) ([]KeyValue, bool) {
+	fields := c.Namespaces[ns].Key
+	if len(fields) == 0 {
+		fields = []string{"_id"}
+	}
+
+	var result []KeyValue
+
+	for _, field := range fields {
+		value, ok := document[field]
+		if !ok {
+			return nil, false
+		}
+
+		result = append(result, KeyValue{Key: field, Value: value})
+	}
+
+	return result, true
+}
+
+func rowID(config Config, ns string, document map[string]interface{}) (string, bool) {
+	key, ok := config.rowKey(ns, document)
+	if !ok {
+		return "", false
+	}
+
+	var values []interface{}
+	for _, field := range key {
+		values = append(values, field.Value)
+	}
+
+	id, err := json.Marshal(values)
+	if err != nil {
+		return "", false
+	}
+
+	return ns + " " + string(id), true
+}
+
+func copyDocument(document map[string]interface{}) map[string]interface{} {
+	if document == nil {
+		return nil
+	}
+
+	result := make(map[string]interface{}, len(document))
+	for field, value := range document {
+		result[field] = value
+	}
+
+	return result
+}
@@ -0,0 +1,237 @@
+package chroma
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// seedQuery lists the columns of every user table, in the columns shared by
+// the information_schema of MySQL and Postgres.
+const seedQuery = `SELECT table_schema, table_name, column_name, data_type, character_maximum_length, is_nullable
+FROM information_schema.columns
+WHERE table_schema NOT IN ('information_schema', 'pg_catalog', 'mysql', 'performance_schema', 'sys')
+ORDER BY table_schema, table_name, ordinal_position`
+
+var (
+	createSchema = regexp.MustCompile(`(?is)^CREATE\s+(?:SCHEMA|DATABASE)\s+(?:IF\s+NOT\s+EXISTS\s+)?([\w"` + "`" + `]+)`)
+	createTable  = regexp.MustCompile(`(?is)^CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([\w."` + "`" + `]+)\s*\((.*)\)`)
+	addColumn    = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+([\w."` + "`" + `]+)\s+ADD\s+(?:COLUMN\s+)?(.*)`)
+	columnDef    = regexp.MustCompile(`(?is)^([\w"` + "`" + `]+)\s+(.*)$`)
+	sqlType      = regexp.MustCompile(`(?i)^([a-z]+(?:\s+(?:varying|precision|zone|with|without|time))*)\s*(?:\(\s*(\d+)\s*\))?`)
+	defaultValue = regexp.MustCompile(`(?i)\bDEFAULT\s+('(?:[^']|'')*'|\S+)`)
+	constraint   = regexp.MustCompile(`(?i)^(PRIMARY|CONSTRAINT|KEY|INDEX|UNIQUE|FOREIGN|CHECK)\b`)
+)
+
+// SeedFromDB seeds the registry of the default converter from a database.
+func SeedFromDB(db *sql.DB) error {
+	return defaultConverter.SeedFromDB(db)
+}
+
+// SeedFromDDL seeds the registry of the default converter from a DDL script.
+func SeedFromDDL(ddl []byte) error {
+	return defaultConverter.SeedFromDDL(ddl)
+}
+
+// SeedFromDB adds the tables of a live database to the registry, read from
+// its information_schema.
+func (c *Converter) SeedFromDB(db *sql.DB) error {
+	rows, err := db.Query(seedQuery)
+	if err != nil {
+		return fmt.Errorf("error reading information_schema: %w", err)
+	}
+	defer rows.Close()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for rows.Next() {
+		var schema, table, name, dataType, nullable string
+		var length sql.NullInt64
+
+		if err := rows.Scan(&schema, &table, &name, &dataType, &length, &nullable); err != nil {
+			return fmt.Errorf("error reading information_schema: %w", err)
+		}
+
+		column := c.Dialect.parseColumnType(dataType)
+
+		if length.Valid && column.Type == "VARCHAR" {
+			column.Length = int(length.Int64)
+		}
+
+		column.NotNull = strings.EqualFold(nullable, "NO")
+
+		c.seedColumn(schema, table, name, column)
+	}
+
+	return rows.Err()
+}
+
+// SeedFromDDL adds the schemas, tables and columns created by a DDL script
+// to the registry.
+func (c *Converter) SeedFromDDL(ddl []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, statement := range splitStatements(string(ddl)) {
+		if match := createSchema.FindStringSubmatch(statement); match != nil {
+			c.schemas[unquote(match[1])] = true
+			continue
+		}
+
+		if match := createTable.FindStringSubmatch(statement); match != nil {
+			schema, table := splitTableName(match[1])
+
+			for _, definition := range splitTopLevel(match[2], ',') {
+				if err := c.seedDefinition(schema, table, definition); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if match := addColumn.FindStringSubmatch(statement); match != nil {
+			schema, table := splitTableName(match[1])
+
+			if err := c.seedDefinition(schema, table, match[2]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Converter) seedDefinition(schema, table, definition string) error {
+	definition = strings.TrimSpace(definition)
+
+	if definition == "" || constraint.MatchString(definition) {
+		return nil
+	}
+
+	match := columnDef.FindStringSubmatch(definition)
+	if match == nil {
+		return fmt.Errorf("could not parse column definition of %s: %s", table, definition)
+	}
+
+	column := c.Dialect.parseColumnType(match[2])
+
+	column.NotNull = strings.Contains(strings.ToUpper(match[2]), "NOT NULL")
+	column.PrimaryKey = strings.Contains(strings.ToUpper(match[2]), "PRIMARY KEY")
+
+	if value := defaultValue.FindStringSubmatch(match[2]); value != nil {
+		column.Default = value[1]
+	}
+
+	c.seedColumn(schema, table, unquote(match[1]), column)
+
+	return nil
+}
+
+func (c *Converter) seedColumn(schema, table, name string, column Column) {
+	if schema != "" {
+		c.schemas[schema] = true
+	}
+
+	t, ok := c.tables[table]
+
+	if !ok {
+		t = Table{Name: table, Schema: make(map[string]Column)}
+		c.tables[table] = t
+	}
+
+	t.Schema[name] = column
+}
+
+// parseColumnType reads a SQL type back into the column types chroma emits,
+// keeping types it does not generate itself verbatim.
+func (d Dialect) parseColumnType(definition string) Column {
+	definition = strings.TrimSpace(definition)
+
+	match := sqlType.FindStringSubmatch(definition)
+	if match == nil {
+		return Column{Type: strings.ToUpper(definition)}
+	}
+
+	length, _ := strconv.Atoi(match[2])
+
+	switch name := strings.ToUpper(match[1]); name {
+	case "VARCHAR", "CHARACTER VARYING":
+		return Column{Type: "VARCHAR", Length: length}
+	case "TEXT", "MEDIUMTEXT", "LONGTEXT":
+		return Column{Type: d.TextType}
+	case "BIGINT", "INT8":
+		return Column{Type: "BIGINT"}
+	case "FLOAT", "DOUBLE", "DOUBLE PRECISION", "REAL", "FLOAT8":
+		return Column{Type: "FLOAT"}
+	case "BOOLEAN", "BOOL", "TINYINT":
+		return Column{Type: "BOOLEAN"}
+	default:
+		if strings.HasPrefix(definition[len(match[0]):], "(") {
+			// DECIMAL(10,2) and the like
+			end := strings.Index(definition, ")")
+			return Column{Type: strings.ToUpper(definition[:end+1])}
+		}
+		if length > 0 {
+			return Column{Type: name + "(" + match[2] + ")"}
+		}
+		return Column{Type: name}
+	}
+}
+
+func splitTableName(name string) (string, string) {
+	name = unquote(name)
+
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		return name[:idx], name[idx+1:]
+	}
+
+	return "", name
+}
+
+func unquote(name string) string {
+	return strings.NewReplacer(`"`, "", "`", "").Replace(name)
+}
+
+// splitStatements splits a script on semicolons outside of string literals.
+func splitStatements(script string) []string {
+	var result []string
+
+	for _, statement := range splitTopLevel(script, ';') {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			result = append(result, statement)
+		}
+	}
+
+	return result
+}
+
+// splitTopLevel splits on sep outside of parentheses and quotes.
+func splitTopLevel(text string, sep rune) []string {
+	var result []string
+	var current strings.Builder
+	depth := 0
+	quoted := false
+
+	for _, r := range text {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == sep && depth == 0:
+			result = append(result, current.String())
+			current.Reset()
+			continue
+		}
+
+		current.WriteRune(r)
+	}
+
+	return append(result, current.String())
+}
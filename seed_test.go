@@ -0,0 +1,90 @@
+package chroma_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	chroma "github.com/Adedunmol/chroma"
+	"strings"
+	"testing"
+)
+
+func TestSeedFromDDL(t *testing.T) {
+	chroma.ResetRegistry()
+
+	ddl := []byte("CREATE SCHEMA IF NOT EXISTS seed;\n" +
+		"CREATE TABLE IF NOT EXISTS seed.student (\n" +
+		"\t`_id` VARCHAR(24) NOT NULL,\n" +
+		"\tname VARCHAR(100) DEFAULT 'unknown',\n" +
+		"\tfee DECIMAL(10,2),\n" +
+		"\tPRIMARY KEY (_id)\n" +
+		");\n" +
+		"ALTER TABLE seed.student ADD COLUMN is_graduated BOOLEAN;\n")
+
+	if err := chroma.SeedFromDDL(ddl); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]chroma.Column{
+		"_id":          {Type: "VARCHAR", Length: 24, NotNull: true},
+		"name":         {Type: "VARCHAR", Length: 100, Default: "'unknown'"},
+		"fee":          {Type: "DECIMAL(10,2)"},
+		"is_graduated": {Type: "BOOLEAN"},
+	}
+
+	for name, column := range want {
+		got, ok := chroma.GetColumn("student", name)
+		if !ok {
+			t.Errorf("should have found column: %s", name)
+			continue
+		}
+
+		if got != column {
+			t.Errorf("got %#v, want %#v", got, column)
+		}
+	}
+
+	if !chroma.GetSchema("seed") {
+		t.Errorf("should have found schema: %s", "seed")
+	}
+
+	insert := newInsert(t, "seed.student", `{"_id": "635b79e231d82a8ab1de863b", "name": "John Doe", "is_graduated": true, "roll_no": 51}`)
+	got, err := insert.Statements()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 || got[0] != "ALTER TABLE student ADD COLUMN roll_no FLOAT;" {
+		t.Errorf("expected only the missing column to be added, got %q", got)
+	}
+}
+
+func TestSeedFromDB(t *testing.T) {
+	chroma.ResetRegistry()
+
+	testRows["information_schema"] = [][]driver.Value{
+		{"live", "student", "_id", "character varying", int64(24), "NO"},
+		{"live", "student", "bio", "text", nil, "YES"},
+		{"live", "student", "roll_no", "double precision", nil, "YES"},
+	}
+
+	db, err := sql.Open("chroma-test", "information_schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := chroma.SeedFromDB(db); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := chroma.GetColumn("student", "_id")
+	if !ok || got != (chroma.Column{Type: "VARCHAR", Length: 24, NotNull: true}) {
+		t.Errorf("got %#v", got)
+	}
+
+	insert := newInsert(t, "live.student", `{"_id": "635b79e231d82a8ab1de863b", "bio": "`+strings.Repeat("a", 300)+`", "roll_no": 51}`)
+
+	if statements, _ := insert.Statements(); len(statements) != 1 {
+		t.Errorf("expected no DDL, got %q", statements)
+	}
+}
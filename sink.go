@@ -0,0 +1,277 @@
+package chroma
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Statement is a single rendered SQL statement and the oplog entry it came from.
+type Statement struct {
+	Index int
+	Entry map[string]interface{}
+	SQL   string
+}
+
+// Sink receives statements in input order. Flush makes everything written so
+// far durable.
+type Sink interface {
+	Write(Statement) error
+	Flush() error
+	Close() error
+}
+
+// ApplyError points a failed statement back at its source oplog entry.
+type ApplyError struct {
+	Statement Statement
+	Err       error
+}
+
+func (e *ApplyError) Error() string {
+	entry, _ := json.Marshal(e.Statement.Entry)
+
+	return fmt.Sprintf("entry %d: %v\n\tstatement: %s\n\toplog: %s", e.Statement.Index, e.Err, e.Statement.SQL, entry)
+}
+
+func (e *ApplyError) Unwrap() error {
+	return e.Err
+}
+
+// SinkFunc is a sink that hands every statement to a function.
+type SinkFunc func(Statement) error
+
+func (f SinkFunc) Write(statement Statement) error {
+	return f(statement)
+}
+
+func (f SinkFunc) Flush() error {
+	return nil
+}
+
+func (f SinkFunc) Close() error {
+	return nil
+}
+
+type fileSink struct {
+	w      io.Writer
+	buffer *bufio.Writer
+	// offset counts the bytes written, starting from where a resumed file ends.
+	offset int64
+}
+
+func NewFileSink(w io.Writer) Sink {
+	return &fileSink{w: w, buffer: bufio.NewWriter(w)}
+}
+
+func (f *fileSink) Write(statement Statement) error {
+	query := statement.SQL
+
+	if !strings.HasSuffix(query, ";") {
+		query += ";"
+	}
+
+	n, err := f.buffer.WriteString(query + "\n")
+	f.offset += int64(n)
+
+	return err
+}
+
+func (f *fileSink) Flush() error {
+	if err := f.buffer.Flush(); err != nil {
+		return err
+	}
+
+	if syncer, ok := f.w.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+
+	return nil
+}
+
+func (f *fileSink) Close() error {
+	err := f.Flush()
+
+	if closer, ok := f.w.(io.Closer); ok {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// ResumeFileSink reopens the output of an interrupted run, dropping whatever
+// was written after the checkpoint.
+func ResumeFileSink(name string, offset int64) (Sink, error) {
+	fileHandle, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fileHandle.Truncate(offset); err != nil {
+		fileHandle.Close()
+		return nil, err
+	}
+
+	if _, err := fileHandle.Seek(offset, io.SeekStart); err != nil {
+		fileHandle.Close()
+		return nil, err
+	}
+
+	return &fileSink{w: fileHandle, buffer: bufio.NewWriter(fileHandle), offset: offset}, nil
+}
+
+// retryDelay is the wait before the first retry of a transient failure; it
+// doubles on every further attempt.
+var retryDelay = 100 * time.Millisecond
+
+type dbSink struct {
+	db        *sql.DB
+	batchSize int
+	retries   int
+	pending   []Statement
+	// err is the failure of the last batch, which is not tried again.
+	err error
+	// committed, when set, is called after every batch is committed.
+	committed func() error
+}
+
+// NewDBSink executes statements against db in transactions of batchSize,
+// retrying a batch up to retries times on transient errors. Batches end
+// between entries, so an entry is committed whole or not at all, and may
+// exceed batchSize for entries with many statements.
+func NewDBSink(db *sql.DB, batchSize, retries int) Sink {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	return &dbSink{db: db, batchSize: batchSize, retries: retries}
+}
+
+func (d *dbSink) Write(statement Statement) error {
+	d.pending = append(d.pending, statement)
+
+	return nil
+}
+
+// Begin commits the batch once it is full, before the statements of the
+// next entry join it.
+func (d *dbSink) Begin(Entry) error {
+	if len(d.pending) >= d.batchSize {
+		return d.Flush()
+	}
+
+	return nil
+}
+
+// discard drops the statements not yet committed.
+func (d *dbSink) discard() {
+	d.pending = d.pending[:0]
+}
+
+func (d *dbSink) Flush() error {
+	if d.err != nil {
+		return d.err
+	}
+
+	if len(d.pending) == 0 {
+		return nil
+	}
+
+	var err error
+	delay := retryDelay
+
+	for attempt := 0; attempt <= d.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		err = d.execBatch()
+
+		if err == nil || !isTransient(err) {
+			break
+		}
+	}
+
+	if err != nil {
+		d.err = err
+		return err
+	}
+
+	d.pending = d.pending[:0]
+
+	if d.committed != nil {
+		return d.committed()
+	}
+
+	return nil
+}
+
+func (d *dbSink) execBatch() error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return &ApplyError{Statement: d.pending[0], Err: err}
+	}
+
+	for _, statement := range d.pending {
+		if _, err := tx.Exec(statement.SQL); err != nil {
+			tx.Rollback()
+			return &ApplyError{Statement: statement, Err: err}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &ApplyError{Statement: d.pending[len(d.pending)-1], Err: err}
+	}
+
+	return nil
+}
+
+// Close flushes what is pending, unless a batch failed already, and closes
+// the database.
+func (d *dbSink) Close() error {
+	err := d.Flush()
+
+	if closeErr := d.db.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// transientErrors are fragments of driver messages worth retrying on.
+var transientErrors = []string{
+	"deadlock",
+	"lock wait timeout",
+	"try restarting transaction",
+	"could not serialize access",
+	"connection reset",
+	"connection refused",
+	"broken pipe",
+	"too many connections",
+}
+
+func isTransient(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+
+	for _, fragment := range transientErrors {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,414 @@
+package chroma_test
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	chroma "github.com/Adedunmol/chroma"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingDriver is a database/sql driver that records committed statements.
+// It rejects DML whose values are not SQL literals, as a database would. A
+// DSN of "fail=<text>" also rejects statements containing text, counting
+// them in failures, and "deadlock=<n>" fails the first n statements with a
+// transient error.
+type recordingDriver struct {
+	mutex     sync.Mutex
+	committed map[string][]string
+	deadlocks map[string]int
+	failures  map[string]int
+}
+
+var testDriver = &recordingDriver{committed: make(map[string][]string), deadlocks: make(map[string]int), failures: make(map[string]int)}
+
+func init() {
+	sql.Register("chroma-test", testDriver)
+}
+
+func (d *recordingDriver) Open(dsn string) (driver.Conn, error) {
+	return &recordingConn{driver: d, dsn: dsn}, nil
+}
+
+// reset forgets what was recorded for a DSN, so that a test starts afresh
+// however often it runs.
+func (d *recordingDriver) reset(dsn string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	delete(d.committed, dsn)
+	delete(d.deadlocks, dsn)
+	delete(d.failures, dsn)
+}
+
+func (d *recordingDriver) statements(dsn string) []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.committed[dsn]
+}
+
+type recordingConn struct {
+	driver  *recordingDriver
+	dsn     string
+	pending []string
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{conn: c, query: query}, nil
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	c.pending = nil
+	return c, nil
+}
+
+func (c *recordingConn) Commit() error {
+	c.driver.mutex.Lock()
+	defer c.driver.mutex.Unlock()
+
+	c.driver.committed[c.dsn] = append(c.driver.committed[c.dsn], c.pending...)
+	c.pending = nil
+
+	return nil
+}
+
+func (c *recordingConn) Rollback() error {
+	c.pending = nil
+	return nil
+}
+
+func (c *recordingConn) exec(query string) error {
+	c.driver.mutex.Lock()
+	defer c.driver.mutex.Unlock()
+
+	if strings.HasPrefix(c.dsn, "deadlock=") && c.driver.deadlocks[c.dsn] < int(c.dsn[len(c.dsn)-1]-'0') {
+		c.driver.deadlocks[c.dsn]++
+		return errors.New("Deadlock found when trying to get lock; try restarting transaction")
+	}
+
+	if strings.HasPrefix(c.dsn, "fail=") && strings.Contains(query, strings.TrimPrefix(c.dsn, "fail=")) {
+		c.driver.failures[c.dsn]++
+		return errors.New("syntax error")
+	}
+
+	if err := checkSQL(query); err != nil {
+		return err
+	}
+
+	c.pending = append(c.pending, query)
+
+	return nil
+}
+
+var (
+	stringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	valueLiteral  = regexp.MustCompile(`^(\?|NULL|TRUE|FALSE|CURRENT_TIMESTAMP|-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?)$`)
+	identifier    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+	insertSQL     = regexp.MustCompile(`^INSERT INTO \S+ \(([^)]*)\) VALUES \((.*)\);?$`)
+	updateSQL     = regexp.MustCompile(`^UPDATE \S+ SET (.*) WHERE (.*?);?$`)
+	deleteSQL     = regexp.MustCompile(`^DELETE FROM \S+ WHERE (.*?);?$`)
+)
+
+// checkSQL rejects inserts, updates and deletes whose values are not SQL
+// literals, such as unquoted strings. Other statements are let through.
+func checkSQL(query string) error {
+	// string literals become placeholders, so their contents cannot confuse
+	// the split on commas below
+	query = stringLiteral.ReplaceAllString(query, "?")
+	if strings.Contains(query, "'") {
+		return fmt.Errorf("syntax error: unterminated string in %s", query)
+	}
+
+	var assignments []string
+
+	switch {
+	case strings.HasPrefix(query, "INSERT"):
+		match := insertSQL.FindStringSubmatch(query)
+		if match == nil {
+			return fmt.Errorf("syntax error: %s", query)
+		}
+
+		columns, values := strings.Split(match[1], ", "), strings.Split(match[2], ", ")
+		if len(columns) != len(values) {
+			return fmt.Errorf("syntax error: %d columns and %d values in %s", len(columns), len(values), query)
+		}
+
+		for _, value := range values {
+			if !valueLiteral.MatchString(value) {
+				return fmt.Errorf("syntax error: %s in %s", value, query)
+			}
+		}
+
+		return nil
+	case strings.HasPrefix(query, "UPDATE"):
+		match := updateSQL.FindStringSubmatch(query)
+		if match == nil {
+			return fmt.Errorf("syntax error: %s", query)
+		}
+
+		assignments = append(strings.Split(match[1], ", "), strings.Split(match[2], " AND ")...)
+	case strings.HasPrefix(query, "DELETE"):
+		match := deleteSQL.FindStringSubmatch(query)
+		if match == nil {
+			return fmt.Errorf("syntax error: %s", query)
+		}
+
+		assignments = strings.Split(match[1], " AND ")
+	default:
+		return nil
+	}
+
+	for _, assignment := range assignments {
+		if name, ok := strings.CutSuffix(assignment, " IS NULL"); ok && identifier.MatchString(name) {
+			continue
+		}
+
+		name, value, ok := strings.Cut(assignment, " = ")
+		if !ok || !identifier.MatchString(name) || !valueLiteral.MatchString(value) {
+			return fmt.Errorf("syntax error: %s in %s", assignment, query)
+		}
+	}
+
+	return nil
+}
+
+type recordingStmt struct {
+	conn  *recordingConn
+	query string
+}
+
+func (s *recordingStmt) Close() error {
+	return nil
+}
+
+func (s *recordingStmt) NumInput() int {
+	return -1
+}
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := s.conn.exec(s.query); err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, ok := testRows[s.conn.dsn]
+	if !ok {
+		return nil, errors.New("not supported")
+	}
+
+	return &recordingRows{rows: rows}, nil
+}
+
+// testRows holds the rows returned to queries, by DSN.
+var testRows = map[string][][]driver.Value{}
+
+type recordingRows struct {
+	rows [][]driver.Value
+}
+
+func (r *recordingRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *recordingRows) Close() error {
+	return nil
+}
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+func TestDBSink(t *testing.T) {
+	oplogs := []byte(`[
+		{"op": "i", "ns": "apply.student", "o": {"_id": "1", "name": "John Doe"}},
+		{"op": "d", "ns": "apply.student", "o": {"_id": "1"}}
+	]`)
+
+	t.Run("apply statements in batches", func(t *testing.T) {
+		chroma.ResetRegistry()
+
+		testDriver.reset("batches")
+
+		db, err := sql.Open("chroma-test", "batches")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := convert(t, oplogs, chroma.NewDBSink(db, 2, 0))
+		if got != nil {
+			t.Fatal(got)
+		}
+
+		committed := testDriver.statements("batches")
+		want := []string{"CREATE SCHEMA", "CREATE TABLE", "INSERT INTO", "DELETE FROM"}
+
+		if len(committed) != len(want) {
+			t.Fatalf("got %d statements, want %d: %v", len(committed), len(want), committed)
+		}
+
+		for idx, prefix := range want {
+			if !strings.HasPrefix(committed[idx], prefix) {
+				t.Errorf("got %s, want prefix %s", committed[idx], prefix)
+			}
+		}
+	})
+
+	t.Run("retry transient errors", func(t *testing.T) {
+		chroma.ResetRegistry()
+
+		testDriver.reset("deadlock=1")
+
+		db, err := sql.Open("chroma-test", "deadlock=1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := convert(t, oplogs, chroma.NewDBSink(db, 10, 1))
+		if got != nil {
+			t.Fatal(got)
+		}
+
+		if len(testDriver.statements("deadlock=1")) != 4 {
+			t.Errorf("got %v", testDriver.statements("deadlock=1"))
+		}
+	})
+
+	t.Run("report the failing entry", func(t *testing.T) {
+		chroma.ResetRegistry()
+
+		testDriver.reset("fail=DELETE")
+
+		db, err := sql.Open("chroma-test", "fail=DELETE")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := convert(t, oplogs, chroma.NewDBSink(db, 10, 3))
+
+		var applyErr *chroma.ApplyError
+		if !errors.As(got, &applyErr) {
+			t.Fatalf("got unexpected error: %v", got)
+		}
+
+		if applyErr.Statement.Index != 1 {
+			t.Errorf("got entry %d, want %d", applyErr.Statement.Index, 1)
+		}
+
+		if len(testDriver.statements("fail=DELETE")) != 0 {
+			t.Errorf("expected the batch to be rolled back, got %v", testDriver.statements("fail=DELETE"))
+		}
+
+		if got := testDriver.failures["fail=DELETE"]; got != 1 {
+			t.Errorf("got %d attempts of the failed batch, want 1", got)
+		}
+	})
+
+	t.Run("reject values that are not literals", func(t *testing.T) {
+		for _, query := range []string{
+			"INSERT INTO student (_id, name) VALUES (1, John Doe)",
+			"UPDATE student SET name = O'Brien WHERE _id = '1'",
+			"DELETE FROM student WHERE _id = 1 OR 1",
+		} {
+			if err := checkSQL(query); err == nil {
+				t.Errorf("expected %s to be rejected", query)
+			}
+		}
+	})
+
+	t.Run("apply quoted values", func(t *testing.T) {
+		chroma.ResetRegistry()
+
+		testDriver.reset("quoted")
+
+		db, err := sql.Open("chroma-test", "quoted")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := convert(t, []byte(`[
+			{"op": "i", "ns": "apply.student", "o": {"_id": "1", "name": "O'Brien, Jo", "age": 12.5, "alumni": false}},
+			{"op": "u", "ns": "apply.student", "o": {"$v": 2, "diff": {"u": {"name": "Jo"}}}, "o2": {"_id": "1"}}
+		]`), chroma.NewDBSink(db, 10, 0))
+		if got != nil {
+			t.Fatal(got)
+		}
+
+		if committed := testDriver.statements("quoted"); len(committed) != 4 {
+			t.Errorf("got %v", committed)
+		}
+	})
+}
+
+func TestFileSink(t *testing.T) {
+	chroma.ResetRegistry()
+
+	var buffer bytes.Buffer
+
+	err := convert(t, []byte(`[
+		{"op": "i", "ns": "file.student", "o": {"_id": "1"}},
+		{"op": "u", "ns": "file.student", "o": {"$v": 2, "diff": {"u": {"name": "Jane"}}}, "o2": {"_id": "1"}},
+		{"op": "d", "ns": "file.student", "o": {"_id": "1"}}
+	]`), chroma.NewFileSink(&buffer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	want := []string{
+		"CREATE SCHEMA IF NOT EXISTS file;",
+		"CREATE TABLE IF NOT EXISTS student (",
+		"\t _id VARCHAR(255) PRIMARY KEY",
+		");",
+		"INSERT INTO student (_id) VALUES ('1');",
+		"ALTER TABLE student ADD COLUMN name VARCHAR(255);",
+		"UPDATE student SET name = 'Jane' WHERE _id = '1';",
+		"DELETE FROM student WHERE _id = '1';",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func convert(t *testing.T, oplogs []byte, sink chroma.Sink) error {
+	t.Helper()
+
+	data, err := chroma.ParseJSONArray(oplogs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = chroma.Convert(chroma.EntriesFrom(data), sink)
+
+	if closeErr := sink.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
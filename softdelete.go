@@ -0,0 +1,38 @@
+package chroma
+
+// SoftDelete keeps deleted rows, marking them as deleted instead.
+type SoftDelete struct {
+	// DeletedAt is set to the time of the delete, and IsDeleted to true.
+	// They default to deleted_at and is_deleted.
+	DeletedAt string `json:"deleted_at"`
+	IsDeleted string `json:"is_deleted"`
+}
+
+// softDelete returns how deletes of a namespace mark their rows, if they do.
+func (c Config) softDelete(ns string) (*SoftDelete, bool) {
+	result, ok := override(c.SoftDelete, c.Namespaces[ns].SoftDelete)
+	if !ok {
+		return nil, false
+	}
+
+	defaults(map[*string]string{
+		&result.DeletedAt: "deleted_at",
+		&result.IsDeleted: "is_deleted",
+	})
+
+	return result, true
+}
+
+// columns lists the columns a soft delete sets, in table order.
+func (s SoftDelete) columns() []string {
+	return []string{s.DeletedAt, s.IsDeleted}
+}
+
+// column is the declaration of one of the columns a soft delete sets.
+func (s SoftDelete) column(d Dialect, name string) Column {
+	if name == s.IsDeleted {
+		return Column{Type: "BOOLEAN", NotNull: true, Default: "FALSE"}
+	}
+
+	return d.timestampColumn()
+}
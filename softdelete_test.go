@@ -0,0 +1,82 @@
+package chroma_test
+
+import (
+	chroma "github.com/Adedunmol/chroma"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSoftDelete(t *testing.T) {
+	t.Run("mark deleted rows", func(t *testing.T) {
+		converter := chroma.NewConverter()
+		converter.Config = chroma.Config{SoftDelete: &chroma.SoftDelete{}}
+
+		got := collect(t, converter, `{"op": "i", "ns": "soft.student", "o": {"_id": "1", "name": "John"}}
+{"op": "d", "ns": "soft.student", "o": {"_id": "1"}, "ts": {"$timestamp": {"t": 1700000000, "i": 1}}}`)
+
+		want := []string{
+			"CREATE SCHEMA IF NOT EXISTS soft;",
+			"CREATE TABLE IF NOT EXISTS student (\n\t _id VARCHAR(255) PRIMARY KEY,\n\t name VARCHAR(255),\n\t deleted_at DATETIME,\n\t is_deleted BOOLEAN NOT NULL DEFAULT FALSE\n);",
+			"INSERT INTO student (_id, name) VALUES ('1', 'John');",
+			"UPDATE student SET deleted_at = '2023-11-14 22:13:20', is_deleted = TRUE WHERE _id = '1'",
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("add the columns to known tables", func(t *testing.T) {
+		converter := chroma.NewConverter()
+		if err := converter.SetDialect("postgres"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := converter.SeedFromDDL([]byte("CREATE TABLE teacher (_id TEXT PRIMARY KEY);")); err != nil {
+			t.Fatal(err)
+		}
+
+		converter.Config = chroma.Config{
+			Namespaces: map[string]chroma.NamespaceConfig{
+				"soft.teacher": {SoftDelete: &chroma.SoftDelete{DeletedAt: "removed_on", IsDeleted: "removed"}},
+			},
+		}
+
+		oplogs := `{"op": "d", "ns": "soft.teacher", "o": {"_id": "1"}}
+{"op": "d", "ns": "soft.teacher", "o": {"_id": "2"}}
+{"op": "d", "ns": "soft.other", "o": {"_id": "3"}}`
+
+		got := collect(t, converter, oplogs)
+
+		want := []string{
+			"ALTER TABLE teacher ADD COLUMN removed_on TIMESTAMP;",
+			"ALTER TABLE teacher ADD COLUMN removed BOOLEAN NOT NULL DEFAULT FALSE;",
+			"UPDATE teacher SET removed_on = CURRENT_TIMESTAMP, removed = TRUE WHERE _id = '1'",
+			"UPDATE teacher SET removed_on = CURRENT_TIMESTAMP, removed = TRUE WHERE _id = '2'",
+			"DELETE FROM other WHERE _id = '3'",
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("keep the row when compacting", func(t *testing.T) {
+		converter := chroma.NewConverter()
+		converter.Config = chroma.Config{SoftDelete: &chroma.SoftDelete{}}
+		converter.Compact = true
+		converter.Undo = chroma.NewUndo()
+
+		got := collect(t, converter, `{"op": "i", "ns": "soft.course", "o": {"_id": "1", "name": "Maths"}}
+{"op": "d", "ns": "soft.course", "o": {"_id": "1"}}`)
+
+		if last := got[len(got)-1]; !strings.HasPrefix(last, "UPDATE course SET deleted_at = CURRENT_TIMESTAMP") {
+			t.Errorf("got %q, want the row marked as deleted", got)
+		}
+
+		if undo := converter.Undo.Statements()[0]; undo != "UPDATE course SET deleted_at = NULL, is_deleted = FALSE WHERE _id = '1'" {
+			t.Errorf("got undo %q", undo)
+		}
+	})
+}
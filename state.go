@@ -0,0 +1,192 @@
+package chroma
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+// State is the schema registry as saved between runs.
+type State struct {
+	Schemas []string         `json:"schemas"`
+	Tables  map[string]Table `json:"tables"`
+}
+
+// LoadState reads a state file, returning an empty state if there is none yet.
+func LoadState(name string) (State, error) {
+	result := State{Tables: make(map[string]Table)}
+
+	data, err := os.ReadFile(name)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return result, nil
+	}
+
+	if err != nil {
+		return result, fmt.Errorf("error reading state %s: %w", name, err)
+	}
+
+	err = json.Unmarshal(data, &result)
+
+	if err != nil {
+		return result, fmt.Errorf("error parsing state %s: %w", name, err)
+	}
+
+	return result, nil
+}
+
+// SaveState saves the registry of the default converter.
+func SaveState(name string) error {
+	return defaultConverter.SaveState(name)
+}
+
+// SnapshotState copies the registry of the default converter.
+func SnapshotState() State {
+	return defaultConverter.Snapshot()
+}
+
+// RestoreState replaces the registry of the default converter.
+func RestoreState(state State) {
+	defaultConverter.Restore(state)
+}
+
+// SaveState writes the current registry to a state file atomically.
+func (c *Converter) SaveState(name string) error {
+	data, err := json.MarshalIndent(c.Snapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := name + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("error writing state %s: %w", name, err)
+	}
+
+	return os.Rename(tmp, name)
+}
+
+// Snapshot copies the current registry.
+func (c *Converter) Snapshot() State {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	result := State{Tables: make(map[string]Table)}
+
+	for name := range c.schemas {
+		result.Schemas = append(result.Schemas, name)
+	}
+
+	sort.Strings(result.Schemas)
+
+	for name, table := range c.tables {
+		schema := make(map[string]Column)
+		for column, definition := range table.Schema {
+			schema[column] = definition
+		}
+
+		result.Tables[name] = Table{Name: table.Name, Schema: schema}
+	}
+
+	return result
+}
+
+// Restore replaces the registry with a saved one.
+func (c *Converter) Restore(state State) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.schemas = make(map[string]bool)
+	c.tables = make(map[string]Table)
+
+	for _, name := range state.Schemas {
+		c.schemas[name] = true
+	}
+
+	for name, table := range state.Tables {
+		if table.Schema == nil {
+			table.Schema = make(map[string]Column)
+		}
+
+		if table.Name == "" {
+			table.Name = name
+		}
+
+		c.tables[name] = table
+	}
+}
+
+// runSchema prints the registry, once loaded from the state file and seeds
+// and extended by the input, if any.
+func runSchema(options Options, w io.Writer) error {
+	converter, err := setup(options)
+	if err != nil {
+		return err
+	}
+
+	if options.Input != "" {
+		if _, err := scanPlan(converter, options); err != nil {
+			return err
+		}
+	}
+
+	state := converter.Snapshot()
+
+	switch options.Format {
+	case "json":
+		data, err := json.MarshalIndent(state, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(w, string(data))
+
+		return err
+	case "ddl", "":
+		state.PrintDDL(w)
+		return nil
+	default:
+		return fmt.Errorf("unknown format %s", options.Format)
+	}
+}
+
+// PrintDDL writes the statements that create the state's schemas and tables.
+func (s State) PrintDDL(w io.Writer) {
+	for _, name := range s.Schemas {
+		fmt.Fprintf(w, "CREATE SCHEMA IF NOT EXISTS %s;\n", name)
+	}
+
+	var names []string
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		table := s.Tables[name]
+
+		columns := make(map[string]interface{})
+		for column, definition := range table.Schema {
+			columns[column] = definition
+		}
+
+		var lines []string
+		for _, column := range sortedEntries(columns) {
+			definition := column.Value.(Column)
+			line := "\t " + column.Key + " " + definition.definition()
+
+			if definition.PrimaryKey {
+				line += " PRIMARY KEY"
+			}
+
+			lines = append(lines, line)
+		}
+
+		fmt.Fprintf(w, "CREATE TABLE IF NOT EXISTS %s (\n%s\n);\n", name, strings.Join(lines, ",\n"))
+	}
+}
@@ -0,0 +1,89 @@
+package chroma_test
+
+import (
+	"bytes"
+	chroma "github.com/Adedunmol/chroma"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestState(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "state.json")
+
+	chroma.ResetRegistry()
+
+	insert := newInsert(t, "state.student", `{"_id": "1", "name": "John Doe"}`)
+	_ = insert.String()
+
+	if err := chroma.SaveState(name); err != nil {
+		t.Fatal(err)
+	}
+
+	chroma.ResetRegistry()
+
+	state, err := chroma.LoadState(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := chroma.State{
+		Schemas: []string{"state"},
+		Tables: map[string]chroma.Table{
+			"student": {
+				Name: "student",
+				Schema: map[string]chroma.Column{
+					"_id":  {Type: "VARCHAR", Length: 255, PrimaryKey: true},
+					"name": {Type: "VARCHAR", Length: 255},
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(state, want) {
+		t.Fatalf("got %#v, want %#v", state, want)
+	}
+
+	chroma.RestoreState(state)
+
+	insert = newInsert(t, "state.student", `{"_id": "2", "name": "Jane Doe", "roll_no": 21}`)
+	got, err := insert.Statements()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 || !strings.HasPrefix(got[0], "ALTER TABLE student ADD COLUMN roll_no") {
+		t.Errorf("expected only the missing column to be added, got %q", got)
+	}
+}
+
+func TestLoadMissingState(t *testing.T) {
+	state, err := chroma.LoadState(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(state.Tables) != 0 || len(state.Schemas) != 0 {
+		t.Errorf("expected an empty state, got %#v", state)
+	}
+}
+
+func TestPrintDDL(t *testing.T) {
+	converter := chroma.NewConverter()
+	converter.Config = chroma.Config{History: &chroma.History{}}
+
+	collect(t, converter, `{"op": "i", "ns": "ddl.student", "o": {"_id": "1"}}`)
+
+	var buf bytes.Buffer
+	converter.Snapshot().PrintDDL(&buf)
+
+	got := buf.String()
+	want := "CREATE SCHEMA IF NOT EXISTS ddl;\n" +
+		"CREATE TABLE IF NOT EXISTS student (\n\t _id VARCHAR(255) PRIMARY KEY\n);\n" +
+		"CREATE TABLE IF NOT EXISTS student_history (\n\t _id VARCHAR(255),\n\t op VARCHAR(255) NOT NULL,\n\t ts NUMERIC(20),\n\t valid_from DATETIME NOT NULL,\n\t valid_to DATETIME\n);\n"
+
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,331 @@
+package chroma
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NamespaceStats counts the operations of one namespace and what converting
+// them produced.
+type NamespaceStats struct {
+	Inserts  int `json:"inserts"`
+	Updates  int `json:"updates"`
+	Deletes  int `json:"deletes"`
+	Commands int `json:"commands"`
+	Noops    int `json:"noops"`
+	// Other counts op codes with a handler registered by the caller.
+	Other int `json:"other"`
+	// Skipped counts entries that produced no statements: filtered, before
+	// the checkpoint, rejected by the schema lock, without a handler,
+	// dropped by a hook or folded into another by compaction.
+	Skipped      int   `json:"skipped"`
+	Statements   int   `json:"statements"`
+	DDL          int   `json:"ddl"`
+	ColumnsAdded int   `json:"columns_added"`
+	BytesIn      int64 `json:"bytes_in"`
+	BytesOut     int64 `json:"bytes_out"`
+}
+
+func (n *NamespaceStats) add(other *NamespaceStats) {
+	n.Inserts += other.Inserts
+	n.Updates += other.Updates
+	n.Deletes += other.Deletes
+	n.Commands += other.Commands
+	n.Noops += other.Noops
+	n.Other += other.Other
+	n.Skipped += other.Skipped
+	n.Statements += other.Statements
+	n.DDL += other.DDL
+	n.ColumnsAdded += other.ColumnsAdded
+	n.BytesIn += other.BytesIn
+	n.BytesOut += other.BytesOut
+}
+
+// Stats records what happened to the entries of a run. It is updated from
+// the reader and from the sink, which run on different goroutines.
+type Stats struct {
+	Namespaces map[string]*NamespaceStats `json:"namespaces"`
+	Total      NamespaceStats             `json:"total"`
+	Entries    int                        `json:"entries"`
+	Filtered   int                        `json:"filtered"`
+	Resumed    int                        `json:"resumed"`
+	Windowed   int                        `json:"windowed"`
+	Rejected   int                        `json:"rejected"`
+	Unhandled  int                        `json:"unhandled"`
+	Dropped    int                        `json:"dropped"`
+	Compacted  int                        `json:"compacted"`
+	Errors     int                        `json:"errors"`
+	Seconds    float64                    `json:"seconds"`
+	Throughput float64                    `json:"entries_per_second"`
+
+	started time.Time
+	mutex   sync.Mutex
+}
+
+func NewStats() *Stats {
+	return &Stats{Namespaces: make(map[string]*NamespaceStats), started: time.Now()}
+}
+
+func (s *Stats) namespace(oplog map[string]interface{}) *NamespaceStats {
+	ns, _ := oplog["ns"].(string)
+
+	counts, ok := s.Namespaces[ns]
+	if !ok {
+		counts = &NamespaceStats{}
+		s.Namespaces[ns] = counts
+	}
+
+	return counts
+}
+
+// Add counts an entry read from the input.
+func (s *Stats) Add(entry Entry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Entries++
+
+	counts := s.namespace(entry.Data)
+	counts.BytesIn += int64(entry.Size)
+
+	switch entry.Data["op"] {
+	case "insert":
+		counts.Inserts++
+	case "update":
+		counts.Updates++
+	case "delete":
+		counts.Deletes++
+	case "command":
+		counts.Commands++
+	case "noop":
+		counts.Noops++
+	default:
+		counts.Other++
+	}
+}
+
+// Filter counts an entry left out by the namespace filter.
+func (s *Stats) Filter(entry Entry) {
+	s.skip(entry, &s.Filtered)
+}
+
+// Resume counts an entry already converted before the checkpoint.
+func (s *Stats) Resume(entry Entry) {
+	s.skip(entry, &s.Resumed)
+}
+
+// Window counts an entry left out by the time window.
+func (s *Stats) Window(entry Entry) {
+	s.skip(entry, &s.Windowed)
+}
+
+// Reject counts an entry held back by the schema lock.
+func (s *Stats) Reject(entry Entry) {
+	s.skip(entry, &s.Rejected)
+}
+
+// Ignore counts an entry with no handler for its op code.
+func (s *Stats) Ignore(entry Entry) {
+	s.skip(entry, &s.Unhandled)
+}
+
+// Drop counts an entry a hook left out.
+func (s *Stats) Drop(entry Entry) {
+	s.skip(entry, &s.Dropped)
+}
+
+// Compact counts an entry folded into another of its row.
+func (s *Stats) Compact(entry Entry) {
+	s.skip(entry, &s.Compacted)
+}
+
+func (s *Stats) skip(entry Entry, counter *int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	*counter++
+	s.namespace(entry.Data).Skipped++
+}
+
+// Written counts a statement written for an entry.
+func (s *Stats) Written(statement Statement) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := s.namespace(statement.Entry)
+	counts.Statements++
+	counts.BytesOut += int64(len(statement.SQL))
+
+	switch {
+	case strings.HasPrefix(statement.SQL, "CREATE "):
+		counts.DDL++
+	case strings.HasPrefix(statement.SQL, "ALTER "):
+		counts.DDL++
+
+		if strings.Contains(statement.SQL, " ADD COLUMN ") {
+			counts.ColumnsAdded++
+		}
+	}
+}
+
+// Finish totals the namespaces and works out the duration and throughput.
+func (s *Stats) Finish() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Total = NamespaceStats{}
+	for _, counts := range s.Namespaces {
+		s.Total.add(counts)
+	}
+
+	s.Seconds = time.Since(s.started).Seconds()
+
+	if s.Seconds > 0 {
+		s.Throughput = float64(s.Entries) / s.Seconds
+	}
+}
+
+func (s *Stats) Print(w io.Writer) {
+	var names []string
+	for name := range s.Namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	format := "%-30s %8d %8d %8d %8d %8d %8d %8d %8d %10d %10d\n"
+
+	fmt.Fprintf(w, "%-30s %8s %8s %8s %8s %8s %8s %8s %8s %10s %10s\n",
+		"namespace", "inserts", "updates", "deletes", "commands", "skipped", "stmts", "ddl", "columns", "bytes in", "bytes out")
+
+	row := func(name string, c *NamespaceStats) {
+		fmt.Fprintf(w, format, name, c.Inserts, c.Updates, c.Deletes, c.Commands+c.Noops, c.Skipped,
+			c.Statements, c.DDL, c.ColumnsAdded, c.BytesIn, c.BytesOut)
+	}
+
+	for _, name := range names {
+		row(name, s.Namespaces[name])
+	}
+
+	row("total", &s.Total)
+
+	fmt.Fprintf(w, "entries: %d\n", s.Entries)
+	fmt.Fprintf(w, "filtered: %d\n", s.Filtered)
+
+	if s.Resumed > 0 {
+		fmt.Fprintf(w, "skipped before checkpoint: %d\n", s.Resumed)
+	}
+
+	if s.Windowed > 0 {
+		fmt.Fprintf(w, "outside -since/-until: %d\n", s.Windowed)
+	}
+
+	if s.Rejected > 0 {
+		fmt.Fprintf(w, "rejected by schema lock: %d\n", s.Rejected)
+	}
+
+	if s.Unhandled > 0 {
+		fmt.Fprintf(w, "without a handler: %d\n", s.Unhandled)
+	}
+
+	if s.Dropped > 0 {
+		fmt.Fprintf(w, "dropped by hooks: %d\n", s.Dropped)
+	}
+
+	if s.Compacted > 0 {
+		fmt.Fprintf(w, "folded by compaction: %d\n", s.Compacted)
+	}
+
+	fmt.Fprintf(w, "errors: %d\n", s.Errors)
+	fmt.Fprintf(w, "duration: %.3fs (%.1f entries/s)\n", s.Seconds, s.Throughput)
+}
+
+func (s *Stats) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+
+	return err
+}
+
+// StatsSink counts the statements written to a sink.
+type StatsSink struct {
+	Sink
+	stats *Stats
+}
+
+func NewStatsSink(sink Sink, stats *Stats) *StatsSink {
+	return &StatsSink{Sink: sink, stats: stats}
+}
+
+func (s *StatsSink) Write(statement Statement) error {
+	if err := s.Sink.Write(statement); err != nil {
+		return err
+	}
+
+	s.stats.Written(statement)
+
+	return nil
+}
+
+func (s *StatsSink) Begin(entry Entry) error {
+	if begin, ok := s.Sink.(interface{ Begin(Entry) error }); ok {
+		return begin.Begin(entry)
+	}
+
+	return nil
+}
+
+func (s *StatsSink) Reject(entry Entry) {
+	s.stats.Reject(entry)
+}
+
+func (s *StatsSink) Drop(entry Entry) {
+	s.stats.Drop(entry)
+}
+
+// runStats converts the input without writing it anywhere and reports what
+// the conversion would do per namespace.
+func runStats(options Options, w io.Writer) error {
+	if options.Input == "" {
+		return NoFileFound
+	}
+
+	converter, err := setup(options)
+	if err != nil {
+		return err
+	}
+
+	options.Follow = false
+	stats := converter.Stats
+
+	err = converter.convertEntries(context.Background(), func(fn func(Entry) error) error {
+		return converter.readInput(context.Background(), options, fn)
+	}, NewFileSink(io.Discard))
+	if err != nil {
+		stats.Errors++
+	}
+
+	stats.Finish()
+
+	switch options.Format {
+	case "json":
+		if writeErr := stats.WriteJSON(w); err == nil {
+			err = writeErr
+		}
+	case "", "text":
+		stats.Print(w)
+	default:
+		return fmt.Errorf("unknown format %s", options.Format)
+	}
+
+	return err
+}
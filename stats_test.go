@@ -0,0 +1,95 @@
+package chroma_test
+
+import (
+	"bytes"
+	"encoding/json"
+	chroma "github.com/Adedunmol/chroma"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "oplog.jsonl")
+
+	lines := []string{
+		`{"op": "i", "ns": "stats.student", "o": {"_id": "1", "name": "John Doe"}}`,
+		`{"op": "i", "ns": "stats.student", "o": {"_id": "2", "name": "Jane Doe", "roll_no": 12}}`,
+		`{"op": "u", "ns": "stats.student", "o": {"$v": 2, "diff": {"u": {"name": "Jo"}}}, "o2": {"_id": "1"}}`,
+		`{"op": "d", "ns": "stats.student", "o": {"_id": "2"}}`,
+		`{"op": "c", "ns": "stats.$cmd", "o": {"create": "teacher"}}`,
+		`{"op": "n", "ns": "", "o": {"msg": "periodic noop"}}`,
+		`{"op": "i", "ns": "other.teacher", "o": {"_id": "1"}}`,
+	}
+
+	if err := os.WriteFile(input, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("json", func(t *testing.T) {
+		chroma.ResetRegistry()
+
+		var stdout, stderr bytes.Buffer
+
+		code := chroma.Execute([]string{"stats", "-i", input, "-exclude", "other.*", "-format", "json"}, &stdout, &stderr)
+		if code != chroma.ExitOK {
+			t.Fatalf("got exit code %d: %s", code, stderr.String())
+		}
+
+		var got chroma.Stats
+		if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Entries != 7 || got.Filtered != 1 || got.Errors != 0 {
+			t.Errorf("got %d entries, %d filtered, %d errors, want 7, 1, 0", got.Entries, got.Filtered, got.Errors)
+		}
+
+		student := got.Namespaces["stats.student"]
+		if student == nil {
+			t.Fatalf("got no stats for stats.student: %s", stdout.String())
+		}
+
+		want := chroma.NamespaceStats{Inserts: 2, Updates: 1, Deletes: 1, Statements: 7, DDL: 3, ColumnsAdded: 1}
+		want.BytesIn, want.BytesOut = student.BytesIn, student.BytesOut
+
+		if *student != want {
+			t.Errorf("got %+v, want %+v", *student, want)
+		}
+
+		if student.BytesIn != int64(len(lines[0])+len(lines[1])+len(lines[2])+len(lines[3])+4) {
+			t.Errorf("got %d bytes in", student.BytesIn)
+		}
+
+		if student.BytesOut == 0 {
+			t.Errorf("expected bytes out to be counted")
+		}
+
+		if cmd := got.Namespaces["stats.$cmd"]; cmd == nil || cmd.Commands != 1 || cmd.Skipped != 1 {
+			t.Errorf("got %+v, want one skipped command", cmd)
+		}
+
+		if got.Total.Noops != 1 || got.Total.Skipped != 3 {
+			t.Errorf("got %d noops and %d skipped in total, want 1 and 3", got.Total.Noops, got.Total.Skipped)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		chroma.ResetRegistry()
+
+		var stdout, stderr bytes.Buffer
+
+		code := chroma.Execute([]string{"stats", "-i", input}, &stdout, &stderr)
+		if code != chroma.ExitOK {
+			t.Fatalf("got exit code %d: %s", code, stderr.String())
+		}
+
+		for _, want := range []string{"stats.student", "other.teacher", "total", "entries: 7", "entries/s"} {
+			if !strings.Contains(stdout.String(), want) {
+				t.Errorf("expected %q in %s", want, stdout.String())
+			}
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package chroma
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Timestamp is a Mongo oplog timestamp: seconds since the epoch and an
+// ordinal for operations within the same second.
+type Timestamp struct {
+	T uint32 `json:"t"`
+	I uint32 `json:"i"`
+}
+
+// ParseTimestamp reads a timestamp in either extended JSON
+// ({"$timestamp": {"t": ..., "i": ...}}) or relaxed ({"t": ..., "i": ...}) form.
+func ParseTimestamp(value interface{}) (Timestamp, bool) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return Timestamp{}, false
+	}
+
+	if inner, ok := object["$timestamp"]; ok {
+		return ParseTimestamp(inner)
+	}
+
+	t, okT := object["t"].(float64)
+	i, okI := object["i"].(float64)
+
+	if !okT || !okI {
+		return Timestamp{}, false
+	}
+
+	return Timestamp{T: uint32(t), I: uint32(i)}, true
+}
+
+func entryTimestamp(oplog map[string]interface{}) (Timestamp, bool) {
+	return ParseTimestamp(oplog["ts"])
+}
+
+// ParseInt reads a number in relaxed or extended JSON ({"$numberLong": "..."}
+// or {"$numberInt": "..."}).
+func ParseInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return int64(v), true
+	case string:
+		result, err := strconv.ParseInt(v, 10, 64)
+		return result, err == nil
+	case map[string]interface{}:
+		if inner, ok := v["$numberLong"]; ok {
+			return ParseInt(inner)
+		}
+		if inner, ok := v["$numberInt"]; ok {
+			return ParseInt(inner)
+		}
+	}
+
+	return 0, false
+}
+
+// ParseDate reads a date in relaxed or extended JSON: {"$date": ...} around
+// an RFC 3339 string or milliseconds since the epoch, or either of those
+// alone.
+func ParseDate(value interface{}) (time.Time, bool) {
+	if object, ok := value.(map[string]interface{}); ok {
+		if inner, ok := object["$date"]; ok {
+			return ParseDate(inner)
+		}
+	}
+
+	if str, ok := value.(string); ok {
+		result, err := time.Parse(time.RFC3339Nano, str)
+		return result, err == nil
+	}
+
+	if millis, ok := ParseInt(value); ok {
+		return time.UnixMilli(millis).UTC(), true
+	}
+
+	return time.Time{}, false
+}
+
+func entryWall(oplog map[string]interface{}) (time.Time, bool) {
+	return ParseDate(oplog["wall"])
+}
+
+// value is the timestamp as one number, the seconds in the high 32 bits
+// and the ordinal in the low ones, as Mongo stores it.
+func (t Timestamp) value() uint64 {
+	return uint64(t.T)<<32 | uint64(t.I)
+}
+
+// entryTime renders the time of an entry as SQL, from its ts, or as the
+// current time if it has none.
+func entryTime(oplog map[string]interface{}) string {
+	ts, ok := entryTimestamp(oplog)
+	if !ok {
+		return "CURRENT_TIMESTAMP"
+	}
+
+	return literal(time.Unix(int64(ts.T), 0).UTC().Format("2006-01-02 15:04:05"))
+}
+
+// tsColumn holds the ts of an entry as one number. It is NUMERIC(20)
+// rather than BIGINT: t<<32|i takes all 64 bits, and outgrows a signed
+// BIGINT once t reaches 2^31.
+func tsColumn() Column {
+	return Column{Type: "NUMERIC", Length: 20}
+}
+
+// timestampLiteral renders the ts of an entry as one number, or NULL.
+func timestampLiteral(oplog map[string]interface{}) string {
+	ts, ok := entryTimestamp(oplog)
+	if !ok {
+		return "NULL"
+	}
+
+	return strconv.FormatUint(ts.value(), 10)
+}
+
+// wallLiteral renders the wall clock time of an entry, or NULL.
+func wallLiteral(oplog map[string]interface{}) string {
+	wall, ok := entryWall(oplog)
+	if !ok {
+		return "NULL"
+	}
+
+	return literal(wall.UTC().Format("2006-01-02 15:04:05.000"))
+}
+
+func (t Timestamp) Compare(other Timestamp) int {
+	switch {
+	case t.T < other.T:
+		return -1
+	case t.T > other.T:
+		return 1
+	case t.I < other.I:
+		return -1
+	case t.I > other.I:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (t Timestamp) String() string {
+	return fmt.Sprintf("%d,%d", t.T, t.I)
+}
@@ -0,0 +1,140 @@
+package chroma
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// Transform masks a field before it is rendered to SQL.
+type Transform struct {
+	// Type is one of redact, hash, hmac, truncate or tokenize.
+	Type string `json:"type"`
+	// Salt is prepended to the value before hashing.
+	Salt string `json:"salt"`
+	// Key is the HMAC key for hmac and tokenize; KeyEnv names an environment variable holding it instead.
+	Key    string `json:"key"`
+	KeyEnv string `json:"key_env"`
+	// Length is the number of characters kept by truncate.
+	Length int `json:"length"`
+	// Value replaces redacted fields, "REDACTED" by default.
+	Value string `json:"value"`
+}
+
+const tokenPrefix = "tok_"
+
+func (t Transform) validate() error {
+	switch t.Type {
+	case "redact", "hash":
+		return nil
+	case "hmac", "tokenize", "tokenise":
+		if t.key() == "" {
+			return fmt.Errorf("%w: %s transform needs a key", ConfigError, t.Type)
+		}
+		return nil
+	case "truncate":
+		if t.Length <= 0 {
+			return fmt.Errorf("%w: truncate transform needs a positive length", ConfigError)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown transform %s", ConfigError, t.Type)
+	}
+}
+
+func (t Transform) key() string {
+	if t.KeyEnv != "" {
+		return os.Getenv(t.KeyEnv)
+	}
+
+	return t.Key
+}
+
+func (t Transform) apply(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	str, isString := value.(string)
+	if !isString {
+		str = fmt.Sprintf("%v", value)
+	}
+
+	switch t.Type {
+	case "redact":
+		if t.Value == "" {
+			return "REDACTED"
+		}
+		return t.Value
+	case "hash":
+		sum := sha256.Sum256([]byte(t.Salt + str))
+		return hex.EncodeToString(sum[:])
+	case "hmac":
+		return t.mac(str)
+	case "tokenize", "tokenise":
+		return tokenPrefix + t.mac(str)[:16]
+	case "truncate":
+		if !isString {
+			return value
+		}
+		runes := []rune(str)
+		if len(runes) > t.Length {
+			return string(runes[:t.Length])
+		}
+		return str
+	default:
+		return value
+	}
+}
+
+func (t Transform) mac(value string) string {
+	mac := hmac.New(sha256.New, []byte(t.key()))
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ApplyTransforms masks the configured fields of a parsed handler, including
+// the fields of update and delete conditions so that keys keep matching.
+// It uses the config of the default converter.
+func ApplyTransforms(data map[string]interface{}, handler Handler) {
+	defaultConverter.applyTransforms(data, handler)
+}
+
+func (c *Converter) applyTransforms(data map[string]interface{}, handler Handler) {
+	ns := getNamespace(data)
+
+	transforms, ok := c.Config.Transforms[ns]
+	if !ok {
+		return
+	}
+
+	byColumn := make(map[string]Transform)
+	for field, transform := range transforms {
+		byColumn[c.Config.rename(ns, field)] = transform
+	}
+
+	switch h := handler.(type) {
+	case *Insert:
+		transformColumns(byColumn, h.Columns)
+	case *Update:
+		transformColumns(byColumn, h.Columns)
+		transformColumns(byColumn, h.Conditions)
+	case *Delete:
+		transformColumns(byColumn, h.Conditions)
+	}
+}
+
+func transformColumns(transforms map[string]Transform, columns []KeyValue) {
+	for idx := range columns {
+		transformColumn(transforms, &columns[idx])
+	}
+}
+
+func transformColumn(transforms map[string]Transform, column *KeyValue) {
+	if transform, ok := transforms[column.Key]; ok {
+		column.Value = transform.apply(column.Value)
+	}
+}
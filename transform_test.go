@@ -0,0 +1,91 @@
+package chroma_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	chroma "github.com/Adedunmol/chroma"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestApplyTransforms(t *testing.T) {
+	chroma.SetConfig(chroma.Config{
+		Transforms: map[string]map[string]chroma.Transform{
+			"pii.student": {
+				"email": {Type: "hash", Salt: "pepper"},
+				"phone": {Type: "redact"},
+				"name":  {Type: "truncate", Length: 4},
+				"_id":   {Type: "tokenize", Key: "secret"},
+			},
+		},
+	})
+	defer chroma.SetConfig(chroma.Config{})
+
+	sum := sha256.Sum256([]byte("pepper" + "john@example.com"))
+	hashed := hex.EncodeToString(sum[:])
+
+	t.Run("insert", func(t *testing.T) {
+		data, err := chroma.ParseJSONMap([]byte(`{"op": "i", "ns": "pii.student", "o": {"_id": "1", "email": "john@example.com", "phone": "+2348000000000", "name": "John Doe"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		insert := chroma.NewInsert()
+		err = insert.Parse(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		chroma.ApplyTransforms(data, &insert)
+
+		want := map[string]interface{}{
+			"email": hashed,
+			"phone": "REDACTED",
+			"name":  "John",
+		}
+
+		for _, column := range insert.Columns {
+			if column.Key == "_id" {
+				if !strings.HasPrefix(column.Value.(string), "tok_") {
+					t.Errorf("expected a token, got %v", column.Value)
+				}
+				continue
+			}
+
+			if column.Value != want[column.Key] {
+				t.Errorf("got %v, want %v for %s", column.Value, want[column.Key], column.Key)
+			}
+		}
+	})
+
+	t.Run("delete condition matches insert", func(t *testing.T) {
+		data, err := chroma.ParseJSONMap([]byte(`{"op": "d", "ns": "pii.student", "o": {"email": "john@example.com"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		deleteOp := chroma.NewDelete()
+		err = deleteOp.Parse(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		chroma.ApplyTransforms(data, &deleteOp)
+
+		if deleteOp.Conditions[0].Value != hashed {
+			t.Errorf("got %v, want %v", deleteOp.Conditions[0].Value, hashed)
+		}
+	})
+}
+
+func TestLoadTransforms(t *testing.T) {
+	fileSystem := fstest.MapFS{"chroma.json": {Data: []byte(`{"transforms": {"pii.student": {"email": {"type": "hmac"}}}}`)}}
+
+	_, err := chroma.LoadConfig(fileSystem, "chroma.json")
+
+	if !errors.Is(err, chroma.ConfigError) {
+		t.Errorf("got unexpected error: %v", err)
+	}
+}
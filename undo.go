@@ -0,0 +1,153 @@
+package chroma
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Undo collects the statements that reverse a conversion, to be run newest
+// first. Inserts are undone by deleting their row. Updates and deletes need
+// the row as it was before, from the preImage of the entry or from an
+// earlier entry of the same run; those without one are listed as comments.
+// Soft deletes are undone by clearing their mark. Schema changes and the
+// versions added to history tables are not undone.
+type Undo struct {
+	statements []string
+}
+
+func NewUndo() *Undo {
+	return &Undo{}
+}
+
+// Statements returns the statements undoing the entries recorded so far,
+// newest first.
+func (u *Undo) Statements() []string {
+	result := make([]string, 0, len(u.statements))
+
+	for idx := len(u.statements) - 1; idx >= 0; idx-- {
+		result = append(result, u.statements[idx])
+	}
+
+	return result
+}
+
+// WriteScript writes the statements undoing the entries recorded so far,
+// newest first, one per line.
+func (u *Undo) WriteScript(w io.Writer) error {
+	for _, statement := range u.Statements() {
+		if !strings.HasPrefix(statement, "--") {
+			statement += ";"
+		}
+
+		if _, err := fmt.Fprintln(w, statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// record adds the statement undoing an entry. It is called in input order,
+// before the rows of the converter take in the entry.
+func (u *Undo) record(c *Converter, entry Entry, handler Handler) {
+	if statement := u.reverse(c, entry, handler); statement != "" {
+		u.statements = append(u.statements, statement)
+	}
+}
+
+func (u *Undo) reverse(c *Converter, entry Entry, handler Handler) string {
+	oplog := entry.Data
+	ns := getNamespace(oplog)
+
+	switch h := handler.(type) {
+	case *Insert:
+		conditions, ok := c.keyConditions(oplog)
+		if !ok {
+			return fmt.Sprintf("-- entry %d: insert into %s not undone, it has no key", entry.Index, h.Table)
+		}
+
+		undo := &Delete{Table: h.Table, Conditions: conditions}
+
+		return undo.String()
+	case *Update:
+		row, ok := c.rows().before(c.Config, oplog)
+		if !ok {
+			return fmt.Sprintf("-- entry %d: update of %s not undone, its row was not seen before", entry.Index, h.Table)
+		}
+
+		var changed []KeyValue
+		for _, field := range updatedFields(oplog) {
+			changed = append(changed, KeyValue{Key: field, Value: row[field]})
+		}
+
+		// the conditions of h are masked already, only the restored values need it
+		restore := &Insert{Table: h.Table, Columns: c.Config.columns(ns, changed)}
+		if len(restore.Columns) == 0 {
+			return ""
+		}
+
+		c.applyTransforms(oplog, restore)
+
+		var columns []string
+		for _, column := range restore.Columns {
+			columns = append(columns, fmt.Sprintf("%s = %s", column.Key, literal(column.Value)))
+		}
+
+		return fmt.Sprintf("UPDATE %s SET %s WHERE %s", h.Table, strings.Join(columns, ", "), where(h.Conditions))
+	case *Delete:
+		if h.softDelete != nil {
+			return fmt.Sprintf("UPDATE %s SET %s = NULL, %s = FALSE WHERE %s", h.Table,
+				h.softDelete.DeletedAt, h.softDelete.IsDeleted, where(h.Conditions))
+		}
+
+		row, ok := c.rows().before(c.Config, oplog)
+		if !ok {
+			return fmt.Sprintf("-- entry %d: delete from %s not undone, its row was not seen before", entry.Index, h.Table)
+		}
+
+		restore := &Insert{Table: h.Table, Columns: c.Config.columns(ns, sortedEntries(row))}
+
+		c.applyTransforms(oplog, restore)
+
+		return restore.values()
+	default:
+		return fmt.Sprintf("-- entry %d: %v not undone", entry.Index, oplog["op"])
+	}
+}
+
+// updatedFields lists the fields an update sets or unsets, sorted.
+func updatedFields(oplog map[string]interface{}) []string {
+	object, _ := oplog["o"].(map[string]interface{})
+	diff, _ := object["diff"].(map[string]interface{})
+
+	fields := make(map[string]interface{})
+
+	for _, operation := range []string{"u", "d"} {
+		if document, ok := diff[operation].(map[string]interface{}); ok {
+			for field := range document {
+				fields[field] = nil
+			}
+		}
+	}
+
+	var result []string
+	for _, field := range sortedEntries(fields) {
+		result = append(result, field.Key)
+	}
+
+	return result
+}
+
+// values renders the insert alone, with its values as literals.
+func (i *Insert) values() string {
+	var columns []string
+	var values []string
+
+	for _, entry := range i.Columns {
+		columns = append(columns, entry.Key)
+		values = append(values, literal(entry.Value))
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", i.Table, strings.Join(columns, ", "), strings.Join(values, ", "))
+}
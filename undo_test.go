@@ -0,0 +1,99 @@
+package chroma_test
+
+import (
+	"bytes"
+	"context"
+	chroma "github.com/Adedunmol/chroma"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUndo(t *testing.T) {
+	oplogs := `{"op": "i", "ns": "undo.student", "o": {"_id": "1", "name": "John", "age": 20}}
+{"op": "u", "ns": "undo.student", "o": {"$v": 2, "diff": {"u": {"name": "Jane"}}}, "o2": {"_id": "1"}}
+{"op": "u", "ns": "undo.student", "o": {"$v": 2, "diff": {"d": {"age": false}}}, "o2": {"_id": "1"}}
+{"op": "d", "ns": "undo.student", "o": {"_id": "1"}}
+{"op": "d", "ns": "undo.student", "o": {"_id": "2"}, "preImage": {"_id": "2", "name": "O'Neil"}}
+{"op": "u", "ns": "undo.student", "o": {"$v": 2, "diff": {"u": {"name": "Joe"}}}, "o2": {"_id": "3"}}`
+
+	converter := chroma.NewConverter()
+	converter.Undo = chroma.NewUndo()
+
+	collect(t, converter, oplogs)
+
+	t.Run("statements", func(t *testing.T) {
+		want := []string{
+			"-- entry 5: update of student not undone, its row was not seen before",
+			"INSERT INTO student (_id, name) VALUES ('2', 'O''Neil')",
+			"INSERT INTO student (_id, name) VALUES ('1', 'Jane')",
+			"UPDATE student SET age = 20 WHERE _id = '1'",
+			"UPDATE student SET name = 'John' WHERE _id = '1'",
+			"DELETE FROM student WHERE _id = '1'",
+		}
+
+		if got := converter.Undo.Statements(); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("script", func(t *testing.T) {
+		var buffer bytes.Buffer
+		if err := converter.Undo.WriteScript(&buffer); err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+		if len(lines) != 6 || !strings.HasSuffix(lines[0], "seen before") || lines[5] != "DELETE FROM student WHERE _id = '1';" {
+			t.Errorf("unexpected script %s", buffer.String())
+		}
+	})
+}
+
+func TestUndoDiff(t *testing.T) {
+	oplogs := `{"op": "i", "ns": "undo.student", "o": {"_id": "1", "name": "John", "age": 20}}
+{"op": "u", "ns": "undo.student", "o": {"$v": 2, "diff": {"u": {"name": "Jane"}, "d": {"age": false}}}, "o2": {"_id": "1"}}`
+
+	converter := chroma.NewConverter()
+	converter.Undo = chroma.NewUndo()
+
+	got := collect(t, converter, oplogs)
+
+	if update := got[len(got)-1]; update != "UPDATE student SET name = 'Jane', age = NULL WHERE _id = '1'" {
+		t.Errorf("got %q, want both halves of the diff", update)
+	}
+
+	want := []string{
+		"UPDATE student SET age = 20, name = 'John' WHERE _id = '1'",
+		"DELETE FROM student WHERE _id = '1'",
+	}
+
+	if got := converter.Undo.Statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUndoKey(t *testing.T) {
+	converter := chroma.NewConverter()
+	converter.Undo = chroma.NewUndo()
+	converter.Config = chroma.Config{
+		Namespaces: map[string]chroma.NamespaceConfig{
+			"undo.order": {Key: []string{"region", "_id"}},
+		},
+	}
+
+	err := converter.ConvertFunc(context.Background(), strings.NewReader(`{"op": "i", "ns": "undo.order", "o": {"_id": "1", "region": "eu", "total": 5}}
+{"op": "u", "ns": "undo.order", "o": {"$v": 2, "diff": {"u": {"total": 7}}}, "o2": {"region": "eu", "_id": "1"}}`), func(chroma.Statement) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"UPDATE order SET total = 5 WHERE region = 'eu' AND _id = '1'",
+		"DELETE FROM order WHERE region = 'eu' AND _id = '1'",
+	}
+
+	if got := converter.Undo.Statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package chroma
 
 import (
 	"errors"
@@ -6,12 +6,21 @@ import (
 	"strings"
 )
 
+// Update sets the fields in Columns and clears those in Unset, from the u
+// and d halves of a diff. Op is u when it sets any field and d otherwise.
 type Update struct {
-	Op        string
-	Database  string
-	Table     string
-	Columns   []KeyValue
-	Condition KeyValue
+	Op         string
+	Database   string
+	Table      string
+	Columns    []KeyValue
+	Unset      []KeyValue
+	Conditions []KeyValue
+
+	converter *Converter
+	// metadata, when set, stamps the row with its oplog entry, the values
+	// of which are in stamps.
+	metadata *Metadata
+	stamps   []KeyValue
 }
 
 func NewUpdate() Update {
@@ -29,24 +38,37 @@ func (u *Update) Parse(data map[string]interface{}) error {
 		return err
 	}
 
-	u.Database = match[1]
-	u.Table = match[2]
+	c := converterOf(u.converter)
 
-	op, err := getOperation(data)
+	u.Database, u.Table = c.Config.namespace(match[1], match[2])
+
+	set, unset, err := getDiff(data)
 	if err != nil {
 		return err
 	}
 
-	u.Op = op
+	u.Op = "d"
+	if len(set) > 0 {
+		u.Op = "u"
+	}
 
-	u.Columns = u.getColumns(data, u.Op)
+	u.Columns = c.Config.columns(ns, set)
+	u.Unset = c.Config.columns(ns, unset)
 
 	query, err := u.getCondition(data)
 
 	if err != nil {
 		return err
 	}
-	u.Condition = query
+
+	u.Conditions, err = c.Config.key(ns, query)
+	if err != nil {
+		return err
+	}
+
+	if metadata, ok := c.Config.metadata(ns); ok {
+		u.metadata, u.stamps = metadata, metadata.stamp(data)
+	}
 
 	return nil
 }
@@ -56,87 +78,118 @@ func (u *Update) String() string {
 	var updateStr string
 
 	for _, c := range u.Columns {
-		if u.Op == "u" {
-			columns = append(columns, fmt.Sprintf("%s = %v", c.Key, c.Value))
-		} else {
-			val := c.Value.(bool)
-			var value string
-
-			if !val {
-				value = "NULL"
-			}
+		columns = append(columns, fmt.Sprintf("%s = %s", c.Key, literal(c.Value)))
+	}
 
-			columns = append(columns, fmt.Sprintf("%s = %s", c.Key, value))
-		}
+	// unset fields are cleared, whatever d holds for them
+	for _, c := range u.Unset {
+		columns = append(columns, fmt.Sprintf("%s = NULL", c.Key))
 	}
 
 	columnsStr := strings.Join(columns, ", ")
-	conditionStr := fmt.Sprintf("%s = %s", u.Condition.Key, u.Condition.Value)
-
-	updateStr = fmt.Sprintf("UPDATE %s SET %s WHERE %s", u.Table, columnsStr, conditionStr)
+	updateStr = fmt.Sprintf("UPDATE %s SET %s%s WHERE %s", u.Table, columnsStr, stamps(u.stamps), where(u.Conditions))
 
 	return updateStr
 }
 
-func getOperation(data map[string]interface{}) (string, error) {
+// ddl adds the fields the update sets to its table, if the table lacks
+// them, widens string columns too short for their new values and adds the
+// columns stamping the row, returning the statements doing so. Tables the
+// registry does not know are left alone.
+func (u *Update) ddl() ([]string, error) {
+	c := converterOf(u.converter)
 
-	op, exists := data["o"].(map[string]interface{})["diff"]
-	if !exists {
-		return "", errors.New("no operation found")
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	table, ok := c.tables[u.Table]
+	if !ok {
+		return nil, nil
 	}
 
-	var operation []string
+	// the columns of the update are typed as those of an insert would be
+	alter := &Insert{Database: u.Database, Table: u.Table, converter: u.converter}
 
-	for key, _ := range op.(map[string]interface{}) {
-		operation = append(operation, key)
+	result, err := alter.alterColumns(table, u.Columns)
+	if err != nil {
+		return nil, fmt.Errorf("could not alter table(%s): %w", u.Table, err)
 	}
 
-	if len(operation) == 0 {
-		return "", errors.New("no operation found")
+	if u.metadata != nil {
+		names, definitions := extraColumns(c.Dialect, nil, nil, u.metadata)
+		result = append(result, c.addColumns(u.Table, names, definitions)...)
 	}
 
-	switch operation[0] {
-	case "u":
-		return "u", nil
-	case "d":
-		return "d", nil
-	default:
-		return "", errors.New("unknown operation")
+	return result, nil
+}
+
+// stamps renders the columns stamping a row as further assignments of SET.
+func stamps(columns []KeyValue) string {
+	var result string
+
+	for _, column := range columns {
+		result += fmt.Sprintf(", %s = %s", column.Key, column.Value)
 	}
 
+	return result
 }
 
-func (u *Update) getColumns(data map[string]interface{}, operation string) []KeyValue {
+// getDiff returns the fields a diff sets and those it unsets, sorted.
+func getDiff(data map[string]interface{}) ([]KeyValue, []KeyValue, error) {
+	object, _ := data["o"].(map[string]interface{})
 
-	var result []KeyValue
+	diff, ok := object["diff"].(map[string]interface{})
+	if !ok || len(diff) == 0 {
+		return nil, nil, errors.New("no operation found")
+	}
 
-	object, ok := data["o"].(map[string]interface{})["diff"].(map[string]interface{})[operation]
+	var set, unset []KeyValue
 
-	if !ok {
-		return result
-	}
+	for operation, fields := range diff {
+		document, ok := fields.(map[string]interface{})
+		if !ok {
+			return nil, nil, errors.New("unknown operation")
+		}
 
-	for key, value := range object.(map[string]interface{}) {
-		data := KeyValue{Key: key, Value: value}
-		result = append(result, data)
+		switch operation {
+		case "u":
+			set = sortedEntries(document)
+		case "d":
+			unset = sortedEntries(document)
+		default:
+			return nil, nil, errors.New("unknown operation")
+		}
 	}
 
-	return result
+	return set, unset, nil
 }
 
-func (u *Update) getCondition(data map[string]interface{}) (KeyValue, error) {
+func (u *Update) getCondition(data map[string]interface{}) ([]KeyValue, error) {
 	condition, exists := data["o2"].(map[string]interface{})
 	if !exists {
-		return KeyValue{}, errors.New("no condition found")
+		return nil, errors.New("no condition found")
 	}
 
-	var result []KeyValue
+	result := sortedEntries(condition)
 
-	for key, value := range condition {
-		result = append(result, KeyValue{Key: key, Value: value})
-	}
 	if len(result) == 0 {
-		return KeyValue{}, errors.New("no condition found")
+		return nil, errors.New("no condition found")
+	}
+	return result, nil
+}
+
+// where joins conditions with AND, rendering their values as literals.
+func where(conditions []KeyValue) string {
+	var result []string
+
+	for _, condition := range conditions {
+		if condition.Value == nil {
+			result = append(result, condition.Key+" IS NULL")
+			continue
+		}
+
+		result = append(result, fmt.Sprintf("%s = %s", condition.Key, literal(condition.Value)))
 	}
-	return result[0], nil
+
+	return strings.Join(result, " AND ")
 }
@@ -1,4 +1,4 @@
-package main_test
+package chroma_test
 
 import (
 	chroma "github.com/Adedunmol/chroma"
@@ -37,11 +37,11 @@ func TestUpdate(t *testing.T) {
 		}
 
 		want := chroma.Update{
-			Op:        "u",
-			Database:  "test",
-			Table:     "student",
-			Columns:   []chroma.KeyValue{{Key: "is_graduated", Value: true}},
-			Condition: chroma.KeyValue{Key: "_id", Value: "635b79e231d82a8ab1de863b"},
+			Op:         "u",
+			Database:   "test",
+			Table:      "student",
+			Columns:    []chroma.KeyValue{{Key: "is_graduated", Value: true}},
+			Conditions: []chroma.KeyValue{{Key: "_id", Value: "635b79e231d82a8ab1de863b"}},
 		}
 
 		if !reflect.DeepEqual(got, want) {
@@ -79,11 +79,11 @@ func TestUpdate(t *testing.T) {
 		}
 
 		want := chroma.Update{
-			Op:        "d",
-			Database:  "test",
-			Table:     "student",
-			Columns:   []chroma.KeyValue{{Key: "roll_no", Value: false}},
-			Condition: chroma.KeyValue{Key: "_id", Value: "635b79e231d82a8ab1de863b"},
+			Op:         "d",
+			Database:   "test",
+			Table:      "student",
+			Unset:      []chroma.KeyValue{{Key: "roll_no", Value: false}},
+			Conditions: []chroma.KeyValue{{Key: "_id", Value: "635b79e231d82a8ab1de863b"}},
 		}
 
 		if !reflect.DeepEqual(got, want) {
@@ -92,6 +92,46 @@ func TestUpdate(t *testing.T) {
 	})
 }
 
+func TestUpdateDiff(t *testing.T) {
+	oplog := []byte(`{
+		"op": "u",
+		"ns": "test.student",
+		"o": {"$v": 2, "diff": {"u": {"name": "Jane", "age": 20}, "d": {"nickname": false}}},
+		"o2": {"_id": "1"}
+	}`)
+
+	data, err := chroma.ParseJSONMap(oplog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// both halves of the diff, however the map is iterated
+	for idx := 0; idx < 10; idx++ {
+		update := chroma.NewUpdate()
+		if err := update.Parse(data); err != nil {
+			t.Fatal(err)
+		}
+
+		want := "UPDATE student SET age = 20, name = 'Jane', nickname = NULL WHERE _id = '1'"
+
+		if got := update.String(); got != want {
+			t.Fatalf("got %s want %s", got, want)
+		}
+	}
+
+	t.Run("reject other operations", func(t *testing.T) {
+		data, err := chroma.ParseJSONMap([]byte(`{"op": "u", "ns": "test.student", "o": {"$v": 2, "diff": {"u": {"name": "Jane"}, "sgrades": {"a": true}}}, "o2": {"_id": "1"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		update := chroma.NewUpdate()
+		if err := update.Parse(data); err == nil {
+			t.Errorf("expected an error for an unknown operation")
+		}
+	})
+}
+
 func TestUpdateString(t *testing.T) {
 	t.Run("test update", func(t *testing.T) {
 
@@ -124,7 +164,7 @@ func TestUpdateString(t *testing.T) {
 
 		got := update.String()
 
-		want := "UPDATE student SET is_graduated = true WHERE _id = 635b79e231d82a8ab1de863b"
+		want := "UPDATE student SET is_graduated = TRUE WHERE _id = '635b79e231d82a8ab1de863b'"
 
 		if !reflect.DeepEqual(got, want) {
 			t.Errorf("got %#v want %#v", got, want)
@@ -162,10 +202,37 @@ func TestUpdateString(t *testing.T) {
 
 		got := update.String()
 
-		want := "UPDATE student SET roll_no = NULL WHERE _id = 635b79e231d82a8ab1de863b"
+		want := "UPDATE student SET roll_no = NULL WHERE _id = '635b79e231d82a8ab1de863b'"
 
 		if !reflect.DeepEqual(got, want) {
 			t.Errorf("got %#v want %#v", got, want)
 		}
 	})
 }
+
+func TestUpdateShardKey(t *testing.T) {
+	oplog := []byte(`{
+		"op": "u",
+		"ns": "test.student",
+		"o":  {"$v": 2, "diff": {"u": {"name": "O'Brien"}}},
+		"o2": {"region": "eu", "_id": "635b79e231d82a8ab1de863b", "year": 2020}
+	}`)
+
+	update := chroma.NewUpdate()
+	data, err := chroma.ParseJSONMap(oplog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = update.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := update.String()
+	want := "UPDATE student SET name = 'O''Brien' WHERE _id = '635b79e231d82a8ab1de863b' AND region = 'eu' AND year = 2020"
+
+	if got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,338 @@
+package chroma
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Diagnostic is a problem with one oplog entry, located in the input.
+type Diagnostic struct {
+	Entry   int    `json:"entry"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Offset  int64  `json:"offset"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Validation is the result of checking every entry of an input.
+type Validation struct {
+	Name        string       `json:"file"`
+	Entries     int          `json:"entries"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type problem struct {
+	field   string
+	message string
+}
+
+// Validate checks an input against the config of the default converter.
+func Validate(name string, data []byte) Validation {
+	return defaultConverter.Validate(name, data)
+}
+
+// Validate checks every entry of a JSON array or JSONL input: its syntax,
+// required fields, namespace and value types. Unlike ParseJSONArray it keeps
+// going after a bad entry, except for syntax errors inside a JSON array,
+// which leave no way to find where the next entry starts.
+func (c *Converter) Validate(name string, data []byte) Validation {
+	result := Validation{Name: name, Diagnostics: []Diagnostic{}}
+	lines := newLineIndex(data)
+
+	report := func(entry int, offset int64, problems []problem) {
+		for _, p := range problems {
+			line, column := lines.position(offset)
+			result.Diagnostics = append(result.Diagnostics, Diagnostic{
+				Entry:   entry,
+				Line:    line,
+				Column:  column,
+				Offset:  offset,
+				Field:   p.field,
+				Message: p.message,
+			})
+		}
+	}
+
+	if isJSONArray(data) {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+
+		if _, err := decoder.Token(); err != nil {
+			report(0, syntaxOffset(err, 0), []problem{{message: err.Error()}})
+			return result
+		}
+
+		for decoder.More() {
+			start := skipSeparators(data, decoder.InputOffset())
+
+			var oplog map[string]interface{}
+
+			err := decoder.Decode(&oplog)
+
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				// The decoder cannot resynchronise after a syntax error, and
+				// its offset is not relative to the entry, so decode the
+				// entry on its own to locate the error.
+				var value interface{}
+				err = json.Unmarshal(data[start:], &value)
+				report(result.Entries, syntaxOffset(err, start), []problem{{message: err.Error()}})
+				return result
+			}
+
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				report(result.Entries, start, []problem{{message: fmt.Sprintf("entry is a %s, not an object", typeErr.Value)}})
+				result.Entries++
+				continue
+			}
+
+			if err != nil {
+				// Only a truncated input is left.
+				report(result.Entries, int64(len(data)), []problem{{message: err.Error()}})
+				return result
+			}
+
+			report(result.Entries, start, c.checkEntry(oplog))
+			result.Entries++
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			report(result.Entries, decoder.InputOffset(), []problem{{message: err.Error()}})
+		}
+
+		return result
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLine)
+
+	var offset int64
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		start := offset + int64(len(line)-len(bytes.TrimLeft(line, " \t\r")))
+		offset += int64(len(line)) + 1
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var oplog map[string]interface{}
+
+		if err := json.Unmarshal(line, &oplog); err != nil {
+			report(result.Entries, syntaxOffset(err, offset-int64(len(line))-1), []problem{{message: err.Error()}})
+		} else {
+			report(result.Entries, start, c.checkEntry(oplog))
+		}
+
+		result.Entries++
+	}
+
+	if err := scanner.Err(); err != nil {
+		report(result.Entries, offset, []problem{{message: err.Error()}})
+	}
+
+	return result
+}
+
+// checkEntry lists what would stop an entry from converting.
+func (c *Converter) checkEntry(oplog map[string]interface{}) []problem {
+	var result []problem
+
+	op, ok := oplog["op"].(string)
+	if !ok {
+		result = append(result, problem{field: "op", message: `missing or non-string field "op"`})
+	} else if op != "i" && op != "u" && op != "d" {
+		if _, ok := opNames[op]; ok || registered(op) {
+			// skipped, or up to a registered handler
+			return result
+		}
+
+		result = append(result, problem{field: "op", message: fmt.Sprintf("%v: %s", UnknownOp, op)})
+	}
+
+	ns, ok := oplog["ns"].(string)
+	if !ok {
+		result = append(result, problem{field: "ns", message: `missing or non-string field "ns"`})
+	} else if _, err := extractNamespace(ns); err != nil {
+		result = append(result, problem{field: "ns", message: fmt.Sprintf("%v: %s", err, ns)})
+	}
+
+	object, ok := oplog["o"].(map[string]interface{})
+	if !ok {
+		return append(result, problem{field: "o", message: `missing or non-object field "o"`})
+	}
+
+	if len(result) > 0 {
+		return result
+	}
+
+	match, _ := extractNamespace(ns)
+	database, table := c.Config.namespace(match[1], match[2])
+	insert := &Insert{Database: database, Table: table, converter: c}
+	source := match[1] + "." + match[2]
+
+	switch op {
+	case "i":
+		result = append(result, c.checkTypes(insert, source, "o", object)...)
+	case "u":
+		if condition, ok := oplog["o2"].(map[string]interface{}); !ok || len(condition) == 0 {
+			result = append(result, problem{field: "o2", message: `missing or empty field "o2" of an update`})
+		}
+
+		diff, ok := object["diff"].(map[string]interface{})
+		if !ok {
+			return append(result, problem{field: "o.diff", message: `missing or non-object field "o.diff" of an update`})
+		}
+
+		if _, _, err := getDiff(oplog); err != nil {
+			result = append(result, problem{field: "o.diff", message: err.Error()})
+		}
+
+		if set, ok := diff["u"].(map[string]interface{}); ok {
+			result = append(result, c.checkTypes(insert, source, "o.diff.u", set)...)
+		}
+	case "d":
+		if len(object) == 0 {
+			result = append(result, problem{field: "o", message: `empty field "o" of a delete`})
+		}
+	}
+
+	return result
+}
+
+// checkTypes reports values that no column type is configured for, after
+// the namespace's excludes and renames are applied.
+func (c *Converter) checkTypes(insert *Insert, ns, field string, object map[string]interface{}) []problem {
+	var result []problem
+
+	for _, entry := range sortedEntries(object) {
+		if column, ok := c.Config.Namespaces[ns].Columns[entry.Key]; ok && column.Exclude {
+			continue
+		}
+
+		if _, err := insert.columnType(c.Config.rename(ns, entry.Key), entry.Value); err != nil {
+			kind := mongoType(entry.Value)
+			if kind == "" {
+				kind = "null"
+			}
+
+			result = append(result, problem{field: field + "." + entry.Key, message: fmt.Sprintf("%v %s", TypeError, kind)})
+		}
+	}
+
+	return result
+}
+
+// syntaxOffset locates an unmarshalling error, whose offset is relative to
+// where unmarshalling started.
+func syntaxOffset(err error, start int64) int64 {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return start + syntaxErr.Offset
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return start + typeErr.Offset
+	}
+
+	return start
+}
+
+// skipSeparators moves past the whitespace and comma in front of an entry.
+func skipSeparators(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',':
+			offset++
+		default:
+			return offset
+		}
+	}
+
+	return offset
+}
+
+// lineIndex turns byte offsets into 1-based lines and columns.
+type lineIndex []int64
+
+func newLineIndex(data []byte) lineIndex {
+	result := lineIndex{0}
+
+	for idx, b := range data {
+		if b == '\n' {
+			result = append(result, int64(idx+1))
+		}
+	}
+
+	return result
+}
+
+func (l lineIndex) position(offset int64) (int, int) {
+	line := sort.Search(len(l), func(idx int) bool { return l[idx] > offset })
+
+	return line, int(offset-l[line-1]) + 1
+}
+
+func (v Validation) Print(w io.Writer) {
+	for _, d := range v.Diagnostics {
+		if d.Field != "" {
+			fmt.Fprintf(w, "%s:%d:%d: entry %d: %s: %s\n", v.Name, d.Line, d.Column, d.Entry, d.Field, d.Message)
+			continue
+		}
+
+		fmt.Fprintf(w, "%s:%d:%d: entry %d: %s\n", v.Name, d.Line, d.Column, d.Entry, d.Message)
+	}
+
+	if len(v.Diagnostics) == 0 {
+		fmt.Fprintf(w, "%d entries ok\n", v.Entries)
+		return
+	}
+
+	fmt.Fprintf(w, "%d entries, %d problems\n", v.Entries, len(v.Diagnostics))
+}
+
+// runValidate checks every entry of the input without converting it.
+func runValidate(options Options, w io.Writer) error {
+	if options.Input == "" {
+		return NoFileFound
+	}
+
+	converter, err := setup(Options{Config: options.Config})
+	if err != nil {
+		return err
+	}
+
+	data, err := readFile(options.Input)
+	if err != nil {
+		return err
+	}
+
+	result := converter.Validate(options.Input, data)
+
+	switch options.Format {
+	case "json":
+		encoded, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(encoded))
+	case "", "text":
+		result.Print(w)
+	default:
+		return fmt.Errorf("unknown format %s", options.Format)
+	}
+
+	if len(result.Diagnostics) > 0 {
+		return fmt.Errorf("%w: %d problems in %d entries", InvalidInput, len(result.Diagnostics), result.Entries)
+	}
+
+	return nil
+}
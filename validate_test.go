@@ -0,0 +1,84 @@
+package chroma_test
+
+import (
+	chroma "github.com/Adedunmol/chroma"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("json array", func(t *testing.T) {
+		data := []byte(`[
+  {"op": "i", "ns": "validate.student", "o": {"_id": "1", "tags": ["a"]}},
+  {"op": "x", "ns": "validate", "o": {}},
+  {"op": "u", "ns": "validate.student", "o": {"$v": 2}},
+  {"op": "d", "ns": "validate.student", "o": {"_id": "1"}},
+  "i"
+]`)
+
+		got := chroma.Validate("oplog.json", data)
+
+		want := []chroma.Diagnostic{
+			{Entry: 0, Line: 2, Column: 3, Offset: 4, Field: "o.tags", Message: "unsupported type array"},
+			{Entry: 1, Line: 3, Column: 3, Offset: 79, Field: "op", Message: "unknown op: x"},
+			{Entry: 1, Line: 3, Column: 3, Offset: 79, Field: "ns", Message: "invalid structure for namespace: validate"},
+			{Entry: 2, Line: 4, Column: 3, Offset: 121, Field: "o2", Message: `missing or empty field "o2" of an update`},
+			{Entry: 2, Line: 4, Column: 3, Offset: 121, Field: "o.diff", Message: `missing or non-object field "o.diff" of an update`},
+			{Entry: 4, Line: 6, Column: 3, Offset: 238, Message: "entry is a string, not an object"},
+		}
+
+		if got.Entries != 5 {
+			t.Errorf("got %d entries, want 5", got.Entries)
+		}
+
+		if !reflect.DeepEqual(got.Diagnostics, want) {
+			t.Errorf("got %+v, want %+v", got.Diagnostics, want)
+		}
+	})
+
+	t.Run("jsonl keeps going after a syntax error", func(t *testing.T) {
+		data := []byte(`{"op": "i", "ns": "validate.student", "o": {"_id": "1"}}
+{"op": "i",
+{"op": "d", "ns": "validate.student", "o": {}}
+`)
+
+		got := chroma.Validate("oplog.jsonl", data)
+
+		if got.Entries != 3 {
+			t.Errorf("got %d entries, want 3", got.Entries)
+		}
+
+		if len(got.Diagnostics) != 2 {
+			t.Fatalf("got %+v, want 2 diagnostics", got.Diagnostics)
+		}
+
+		if d := got.Diagnostics[0]; d.Entry != 1 || d.Line != 2 {
+			t.Errorf("got entry %d line %d, want entry 1 line 2", d.Entry, d.Line)
+		}
+
+		if d := got.Diagnostics[1]; d.Entry != 2 || d.Line != 3 || d.Column != 1 || d.Field != "o" {
+			t.Errorf("got %+v, want entry 2 at 3:1 on o", d)
+		}
+	})
+
+	t.Run("syntax error in an array", func(t *testing.T) {
+		data := []byte("[\n  {\"op\": \"i\", \"ns\": \"validate.student\", \"o\": {}},\n  {\"op\" \"i\"}\n]")
+
+		got := chroma.Validate("oplog.json", data)
+
+		if len(got.Diagnostics) != 1 {
+			t.Fatalf("got %+v, want 1 diagnostic", got.Diagnostics)
+		}
+
+		d := got.Diagnostics[0]
+
+		if d.Entry != 1 || d.Line != 3 {
+			t.Errorf("got entry %d line %d, want entry 1 line 3", d.Entry, d.Line)
+		}
+
+		if !strings.Contains(d.Message, "invalid character") {
+			t.Errorf("got %q, want a syntax error", d.Message)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package chroma
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Window keeps the entries of an oplog between two points in time, both
+// included. Either end may be left open.
+type Window struct {
+	Since *Bound
+	Until *Bound
+}
+
+// Bound is a point in time: an oplog ts, or a wall clock time.
+type Bound struct {
+	Timestamp *Timestamp
+	Wall      *time.Time
+}
+
+var BoundError = errors.New("invalid time bound")
+
+// wallLayouts are the wall clock times ParseBound accepts besides RFC 3339.
+// Times without a zone are in UTC, as in the statements written.
+var wallLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// ParseBound reads a bound as an oplog ts, t,i, or as a wall clock time in
+// RFC 3339 or YYYY-MM-DD[ HH:MM[:SS]].
+func ParseBound(value string) (Bound, error) {
+	value = strings.TrimSpace(value)
+
+	if t, i, ok := strings.Cut(value, ","); ok {
+		seconds, errT := strconv.ParseUint(strings.TrimSpace(t), 10, 32)
+		ordinal, errI := strconv.ParseUint(strings.TrimSpace(i), 10, 32)
+
+		if errT != nil || errI != nil {
+			return Bound{}, fmt.Errorf("%w: %s", BoundError, value)
+		}
+
+		return Bound{Timestamp: &Timestamp{T: uint32(seconds), I: uint32(ordinal)}}, nil
+	}
+
+	if wall, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return Bound{Wall: &wall}, nil
+	}
+
+	for _, layout := range wallLayouts {
+		if wall, err := time.Parse(layout, value); err == nil {
+			return Bound{Wall: &wall}, nil
+		}
+	}
+
+	return Bound{}, fmt.Errorf("%w: %s", BoundError, value)
+}
+
+// NewWindow parses the ends of a window, leaving empty ones open.
+func NewWindow(since, until string) (Window, error) {
+	var window Window
+
+	for _, end := range []struct {
+		value string
+		bound **Bound
+	}{{since, &window.Since}, {until, &window.Until}} {
+		if end.value == "" {
+			continue
+		}
+
+		bound, err := ParseBound(end.value)
+		if err != nil {
+			return window, err
+		}
+
+		*end.bound = &bound
+	}
+
+	return window, nil
+}
+
+// Allow reports whether the oplog entry falls within the window. Entries
+// whose time is unknown are let through.
+func (w Window) Allow(oplog map[string]interface{}) bool {
+	if w.Since != nil {
+		if order, ok := w.Since.compare(oplog); ok && order < 0 {
+			return false
+		}
+	}
+
+	if w.Until != nil {
+		if order, ok := w.Until.compare(oplog); ok && order > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compare orders an entry against the bound: by ts for ts bounds, and by
+// wall, or else the seconds of ts, for wall clock bounds.
+func (b Bound) compare(oplog map[string]interface{}) (int, bool) {
+	ts, hasTs := entryTimestamp(oplog)
+
+	if b.Timestamp != nil {
+		if !hasTs {
+			return 0, false
+		}
+
+		return ts.Compare(*b.Timestamp), true
+	}
+
+	if b.Wall == nil {
+		return 0, false
+	}
+
+	wall, ok := entryWall(oplog)
+
+	if !ok && hasTs {
+		wall, ok = time.Unix(int64(ts.T), 0), true
+	}
+
+	if !ok {
+		return 0, false
+	}
+
+	return wall.Compare(*b.Wall), true
+}
@@ -0,0 +1,87 @@
+package chroma_test
+
+import (
+	"errors"
+	chroma "github.com/Adedunmol/chroma"
+	"reflect"
+	"testing"
+)
+
+func TestWindow(t *testing.T) {
+	ts := func(t, i float64) map[string]interface{} {
+		return map[string]interface{}{"ts": map[string]interface{}{"$timestamp": map[string]interface{}{"t": t, "i": i}}}
+	}
+
+	// 1700000000 is 2023-11-14 22:13:20 UTC
+	cases := []struct {
+		name  string
+		since string
+		until string
+		entry map[string]interface{}
+		want  bool
+	}{
+		{"open", "", "", ts(1700000000, 1), true},
+		{"ts since", "1700000000,2", "", ts(1700000000, 1), false},
+		{"ts since included", "1700000000,1", "", ts(1700000000, 1), true},
+		{"ts until", "", "1700000000,1", ts(1700000000, 2), false},
+		{"ts until included", "", "1700000000,2", ts(1700000000, 2), true},
+		{"wall since", "2023-11-14 22:13:21", "", ts(1700000000, 1), false},
+		{"wall until", "", "2023-11-14T22:13:19Z", ts(1700000000, 1), false},
+		{"wall within", "2023-11-14 22:00", "2023-11-14T22:15:00+00:00", ts(1700000000, 1), true},
+		{"wall of entry", "2023-11-14 22:13:21", "", map[string]interface{}{"wall": map[string]interface{}{"$date": "2023-11-14T22:13:21.500Z"}}, true},
+		{"unknown time passes", "1700000000,2", "2023-11-14", map[string]interface{}{}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			window, err := chroma.NewWindow(c.since, c.until)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := window.Allow(c.entry); got != c.want {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	t.Run("invalid bound", func(t *testing.T) {
+		for _, value := range []string{"yesterday", "1700000000,x", "1700000000"} {
+			if _, err := chroma.ParseBound(value); !errors.Is(err, chroma.BoundError) {
+				t.Errorf("%s: got unexpected error: %v", value, err)
+			}
+		}
+	})
+
+	t.Run("combined with a checkpoint", func(t *testing.T) {
+		oplogs := `{"op": "i", "ns": "window.student", "o": {"_id": "1"}, "ts": {"$timestamp": {"t": 1700000000, "i": 1}}}
+{"op": "i", "ns": "window.student", "o": {"_id": "2"}, "ts": {"$timestamp": {"t": 1700000060, "i": 1}}}
+{"op": "i", "ns": "window.student", "o": {"_id": "3"}, "ts": {"$timestamp": {"t": 1700000120, "i": 1}}}
+{"op": "i", "ns": "window.student", "o": {"_id": "4"}, "ts": {"$timestamp": {"t": 1700000180, "i": 1}}}`
+
+		converter := chroma.NewConverter()
+		converter.Resume = &chroma.Checkpoint{Index: 1, Timestamp: &chroma.Timestamp{T: 1700000060, I: 1}}
+
+		var err error
+		converter.Window, err = chroma.NewWindow("2023-11-14 22:13:20", "1700000120,1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := collect(t, converter, oplogs)
+
+		want := []string{
+			"CREATE SCHEMA IF NOT EXISTS window;",
+			"CREATE TABLE IF NOT EXISTS student (\n\t _id VARCHAR(255) PRIMARY KEY\n);",
+			"INSERT INTO student (_id) VALUES ('3');",
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+
+		if converter.Stats.Resumed != 2 || converter.Stats.Windowed != 1 {
+			t.Errorf("got %d resumed and %d outside, want 2 and 1", converter.Stats.Resumed, converter.Stats.Windowed)
+		}
+	})
+}